@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"dashboard-case-study/pkg/models"
 	"dashboard-case-study/pkg/repository"
 	"dashboard-case-study/pkg/service"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
@@ -35,14 +41,61 @@ func main() {
 	employeeRepo := repository.NewPostgresEmployeeRepository(db)
 	orgRepo := repository.NewPostgresOrgRepository(db)
 	responseRepo := repository.NewPostgresResponseRepository(db)
+	erasureRepo := repository.NewPostgresErasureRepository(db)
+	surveyRepo := repository.NewPostgresSurveyRepository(db)
+	dashboardRepo := repository.NewPostgresDashboardRepository(db)
 
 	// Initialize services
 	snapshotSvc := service.NewSnapshotService(employeeRepo, orgRepo)
-	responseSvc := service.NewResponseService(responseRepo, snapshotSvc)
-	dashboardSvc := service.NewDashboardService(responseRepo, orgRepo)
+	if key := os.Getenv("PSEUDONYM_KEY"); key != "" {
+		snapshotSvc.SetPrivacyPolicy(
+			models.SnapshotPrivacyPolicy{Identifiers: models.IdentifierPolicyPseudonymise},
+			service.NewPseudonymizer([]byte(key)),
+		)
+		log.Println("✓ Snapshot identifiers pseudonymised")
+	}
+	responseSvc := service.NewResponseService(responseRepo, surveyRepo, snapshotSvc)
+	dashboardSvc := service.NewDashboardService(responseRepo, orgRepo, surveyRepo)
+	dashboardSvc.SetSchemaRepository(responseRepo)
+	if dir := os.Getenv("ARCHIVE_DIR"); dir != "" {
+		dashboardSvc.SetArchiveStore(repository.NewLocalArchiveStore(dir))
+		log.Printf("✓ Archived months read from %s", dir)
+	}
+	// Receipt digests are keyed with ERASURE_DIGEST_KEY; without it only the erasure
+	// endpoints are unavailable, so existing deployments keep serving everything else
+	var erasureSvc *service.ErasureService
+	if digestKey := os.Getenv("ERASURE_DIGEST_KEY"); digestKey != "" {
+		erasureSvc = service.NewErasureService(erasureRepo, []byte(digestKey))
+	} else {
+		log.Println("⚠ ERASURE_DIGEST_KEY not set: erasure endpoints disabled")
+	}
+	surveySvc := service.NewSurveyService(surveyRepo)
+	savedDashboardSvc := service.NewSavedDashboardService(dashboardRepo, dashboardSvc)
+	exportSvc := service.NewExportService(dashboardSvc, repository.NewPostgresExportJobRepository(db))
+
+	// Materialized view refresh; replicas elect one refresher via an advisory lock
+	refreshRepo := repository.NewPostgresViewRefreshRepository(db)
+	dashboardSvc.SetRefreshRepository(refreshRepo)
+	refreshScheduler := service.NewRefreshScheduler(refreshRepo, repository.NewAdvisoryLock(db, "refresh_dashboard_views"))
+	go refreshScheduler.Run(context.Background())
+
+	// Monthly partitions of survey_responses are created ahead by a single elected replica
+	partitionMgr := service.NewPartitionManager(repository.NewPostgresPartitionRepository(db), repository.NewAdvisoryLock(db, "partition_manager"))
+	go partitionMgr.Run(context.Background())
 
 	// Setup router
 	r := mux.NewRouter()
+	r.Use(withRequestID)
+
+	// Caller permissions. The API does not authenticate callers itself: X-Permissions is
+	// only trusted from an authenticating gateway that proves itself with GATEWAY_TOKEN,
+	// or from anyone when DEV_TRUST_PERMISSIONS_HEADER=1 for local development. Other
+	// callers get no permissions, so identifiers stay redacted and admin endpoints refuse.
+	trustHeader := os.Getenv("DEV_TRUST_PERMISSIONS_HEADER") == "1"
+	if trustHeader {
+		log.Println("⚠ X-Permissions is trusted from every caller (development only)")
+	}
+	r.Use(withPermissions(os.Getenv("GATEWAY_TOKEN"), trustHeader))
 
 	// Health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -52,6 +105,136 @@ func main() {
 		})
 	}).Methods("GET")
 
+	// Survey definition endpoints
+	r.HandleFunc("/api/v1/surveys", func(w http.ResponseWriter, r *http.Request) {
+		var survey models.Survey
+		if err := json.NewDecoder(r.Body).Decode(&survey); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+		survey.TenantID = "tenant_demo"
+
+		err := surveySvc.Create(r.Context(), &survey)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(survey)
+	}).Methods("POST")
+
+	r.HandleFunc("/api/v1/surveys", func(w http.ResponseWriter, r *http.Request) {
+		surveys, err := surveySvc.List(r.Context(), "tenant_demo")
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(surveys)
+	}).Methods("GET")
+
+	r.HandleFunc("/api/v1/surveys/{surveyId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		survey, err := surveySvc.Get(r.Context(), "tenant_demo", vars["surveyId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(survey)
+	}).Methods("GET")
+
+	r.HandleFunc("/api/v1/surveys/{surveyId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		var survey models.Survey
+		if err := json.NewDecoder(r.Body).Decode(&survey); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+		survey.SurveyID = vars["surveyId"]
+		survey.TenantID = "tenant_demo"
+
+		err := surveySvc.Update(r.Context(), &survey)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(survey)
+	}).Methods("PUT")
+
+	r.HandleFunc("/api/v1/surveys/{surveyId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		err := surveySvc.Delete(r.Context(), "tenant_demo", vars["surveyId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Survey lifecycle endpoint (DRAFT → OPEN launches the survey)
+	r.HandleFunc("/api/v1/surveys/{surveyId}/status", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		var req models.SurveyStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+
+		survey, err := surveySvc.Transition(r.Context(), "tenant_demo", vars["surveyId"], req.Status)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(survey)
+	}).Methods("POST")
+
+	// Participation endpoint
+	r.HandleFunc("/api/v1/surveys/{surveyId}/participation", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		participation, err := surveySvc.Participation(r.Context(), "tenant_demo", vars["surveyId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(participation)
+	}).Methods("GET")
+
+	// Participation by org unit endpoint (?mode=HISTORICAL|CURRENT)
+	r.HandleFunc("/api/v1/surveys/{surveyId}/participation/units", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		mode := models.FilterMode(r.URL.Query().Get("mode"))
+		if mode == "" {
+			mode = models.FilterModeHistorical
+		}
+
+		result, err := dashboardSvc.ParticipationByUnit(r.Context(), "tenant_demo", vars["surveyId"], mode)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}).Methods("GET")
+
 	// Submit response endpoint
 	r.HandleFunc("/api/v1/surveys/{surveyId}/responses", func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -59,14 +242,15 @@ func main() {
 
 		var req models.SubmitResponseRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			writeInvalidBody(w, r)
 			return
 		}
 
 		// Submit response (tenant_id would come from JWT in production)
-		response, err := responseSvc.Submit(r.Context(), surveyID, req.EmployeeID, "tenant_demo", req.Answers)
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		response, err := responseSvc.Submit(r.Context(), surveyID, req.EmployeeID, "tenant_demo", req.Answers, idempotencyKey)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to submit response: %v", err), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
@@ -77,18 +261,294 @@ func main() {
 		})
 	}).Methods("POST")
 
+	// Amend response endpoint (respondent only)
+	r.HandleFunc("/api/v1/surveys/{surveyId}/responses/{responseId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		var req models.UpdateResponseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+
+		response, err := responseSvc.Update(r.Context(), "tenant_demo", vars["responseId"], req.EmployeeID, req.Answers)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}).Methods("PUT")
+
+	// Withdraw (respondent) or void (admin) response endpoint
+	r.HandleFunc("/api/v1/surveys/{surveyId}/responses/{responseId}/withdraw", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		var req models.WithdrawResponseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+
+		var err error
+		if req.EmployeeID != "" {
+			err = responseSvc.Withdraw(r.Context(), "tenant_demo", vars["responseId"], req.EmployeeID, req.Reason)
+		} else {
+			err = responseSvc.Void(r.Context(), "tenant_demo", vars["responseId"], req.ActorID, req.Reason)
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("POST")
+
+	// Response version history endpoint (admin)
+	r.HandleFunc("/api/v1/surveys/{surveyId}/responses/{responseId}/versions", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		versions, err := responseSvc.GetVersions(r.Context(), "tenant_demo", vars["responseId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(versions)
+	}).Methods("GET")
+
 	// Query dashboard endpoint
 	r.HandleFunc("/api/v1/dashboards/query", func(w http.ResponseWriter, r *http.Request) {
 		var query models.DashboardQuery
 		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			writeInvalidBody(w, r)
+			return
+		}
+
+		if strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+			streamResponses(w, r, dashboardSvc, query)
 			return
 		}
 
 		// Execute query
 		result, err := dashboardSvc.Query(r.Context(), query)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Query failed: %v", err), http.StatusInternalServerError)
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}).Methods("POST")
+
+	// Period-over-period comparison endpoint
+	r.HandleFunc("/api/v1/dashboards/compare", func(w http.ResponseWriter, r *http.Request) {
+		var query models.ComparisonQuery
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+		query.Query.TenantID = "tenant_demo"
+
+		result, err := dashboardSvc.Compare(r.Context(), query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}).Methods("POST")
+
+	// Free-text comments endpoint
+	r.HandleFunc("/api/v1/dashboards/comments", func(w http.ResponseWriter, r *http.Request) {
+		var query models.CommentQuery
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+		query.Query.TenantID = "tenant_demo"
+
+		result, err := dashboardSvc.Comments(r.Context(), query)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}).Methods("POST")
+
+	// Export endpoint: small exports stream directly, async ones return a job
+	r.HandleFunc("/api/v1/dashboards/export", func(w http.ResponseWriter, r *http.Request) {
+		var req models.ExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+		req.Query.TenantID = "tenant_demo"
+
+		if req.Async {
+			job, err := exportSvc.Start(r.Context(), req)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Location", "/api/v1/exports/"+job.JobID)
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(job)
+			return
+		}
+
+		// Headers are only sent with the first byte, so errors found earlier still get a status
+		out := &exportWriter{w: w, format: req.Format, name: "export"}
+		_, err := exportSvc.Export(r.Context(), req, out)
+		if err != nil && !out.started {
+			writeError(w, r, err)
+			return
+		}
+		if err != nil {
+			log.Printf("Export failed mid-stream [%s]: %v", requestID(r.Context()), err)
+		}
+	}).Methods("POST")
+
+	r.HandleFunc("/api/v1/exports/{jobId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		job, err := exportSvc.Job(r.Context(), "tenant_demo", vars["jobId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if job == nil {
+			writeError(w, r, fmt.Errorf("export %w: %s", repository.ErrNotFound, vars["jobId"]))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	}).Methods("GET")
+
+	r.HandleFunc("/api/v1/exports/{jobId}/download", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		job, err := exportSvc.Job(r.Context(), "tenant_demo", vars["jobId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if job == nil {
+			writeError(w, r, fmt.Errorf("export %w: %s", repository.ErrNotFound, vars["jobId"]))
+			return
+		}
+
+		// Download re-checks the job and the caller's permissions before streaming it
+		out := &exportWriter{w: w, format: job.Format, name: "export-" + job.JobID}
+		err = exportSvc.Download(r.Context(), "tenant_demo", job.JobID, out)
+		if err != nil && !out.started {
+			writeError(w, r, err)
+			return
+		}
+		if err != nil {
+			log.Printf("Export download failed mid-stream [%s]: %v", requestID(r.Context()), err)
+		}
+	}).Methods("GET")
+
+	// Saved dashboard endpoints
+	r.HandleFunc("/api/v1/dashboards", func(w http.ResponseWriter, r *http.Request) {
+		var dashboard models.Dashboard
+		if err := json.NewDecoder(r.Body).Decode(&dashboard); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+		dashboard.TenantID = "tenant_demo"
+
+		err := savedDashboardSvc.Create(r.Context(), &dashboard)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(dashboard)
+	}).Methods("POST")
+
+	r.HandleFunc("/api/v1/dashboards", func(w http.ResponseWriter, r *http.Request) {
+		dashboards, err := savedDashboardSvc.List(r.Context(), "tenant_demo")
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dashboards)
+	}).Methods("GET")
+
+	r.HandleFunc("/api/v1/dashboards/{dashboardId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		dashboard, err := savedDashboardSvc.Get(r.Context(), "tenant_demo", vars["dashboardId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dashboard)
+	}).Methods("GET")
+
+	r.HandleFunc("/api/v1/dashboards/{dashboardId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		var dashboard models.Dashboard
+		if err := json.NewDecoder(r.Body).Decode(&dashboard); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+		dashboard.DashboardID = vars["dashboardId"]
+		dashboard.TenantID = "tenant_demo"
+
+		err := savedDashboardSvc.Update(r.Context(), &dashboard)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dashboard)
+	}).Methods("PUT")
+
+	r.HandleFunc("/api/v1/dashboards/{dashboardId}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		err := savedDashboardSvc.Delete(r.Context(), "tenant_demo", vars["dashboardId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Saved dashboard query endpoint (runs every widget; the body holds optional overrides)
+	r.HandleFunc("/api/v1/dashboards/{dashboardId}/query", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		var req models.DashboardRunRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeInvalidBody(w, r)
+				return
+			}
+		}
+
+		result, err := savedDashboardSvc.Run(r.Context(), "tenant_demo", vars["dashboardId"], req)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
@@ -96,9 +556,214 @@ func main() {
 		json.NewEncoder(w).Encode(result)
 	}).Methods("POST")
 
+	// Right-to-erasure endpoint
+	r.HandleFunc("/api/v1/employees/{employeeId}/erasure", func(w http.ResponseWriter, r *http.Request) {
+		if erasureSvc == nil {
+			writeErasureDisabled(w, r)
+			return
+		}
+		vars := mux.Vars(r)
+		employeeID := vars["employeeId"]
+
+		var req models.ErasureRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeInvalidBody(w, r)
+			return
+		}
+
+		receipt, err := erasureSvc.Erase(r.Context(), "tenant_demo", employeeID, req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(receipt)
+	}).Methods("POST")
+
+	// Erasure receipt lookup
+	r.HandleFunc("/api/v1/erasure-receipts/{receiptId}", func(w http.ResponseWriter, r *http.Request) {
+		if erasureSvc == nil {
+			writeErasureDisabled(w, r)
+			return
+		}
+		vars := mux.Vars(r)
+
+		receipt, err := erasureSvc.GetReceipt(r.Context(), "tenant_demo", vars["receiptId"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(receipt)
+	}).Methods("GET")
+
 	// Start server
 	port := ":8080"
 	log.Printf("🚀 Server starting on http://localhost%s", port)
 	log.Printf("   Health: http://localhost%s/health", port)
 	log.Fatal(http.ListenAndServe(port, r))
 }
+
+// streamResponses writes one JSON response per line as rows are read. An error after
+// the first line aborts the connection so the client sees a truncated stream rather
+// than a complete-looking one.
+func streamResponses(w http.ResponseWriter, r *http.Request, dashboardSvc *service.DashboardService, query models.DashboardQuery) {
+	const flushEvery = 100
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	rows := 0
+
+	err := dashboardSvc.StreamResponses(r.Context(), query, func(resp *models.Response) error {
+		if rows == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+		rows++
+		if flusher != nil && rows%flushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		if rows == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	case r.Context().Err() != nil:
+		// Client disconnected; the query was cancelled with the request context
+	case rows == 0:
+		writeError(w, r, err)
+	default:
+		log.Printf("Response stream failed after %d rows [%s]: %v", rows, requestID(r.Context()), err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportWriter sets the download headers when the export writes its first byte
+type exportWriter struct {
+	w       http.ResponseWriter
+	format  models.ExportFormat
+	name    string // File name without extension
+	started bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", service.ContentType(e.format))
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, e.name, e.format))
+	}
+	return e.w.Write(p)
+}
+
+// apiError is the body of every error response
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Code      string               `json:"code"`
+	Message   string               `json:"message"`
+	Details   []service.FieldError `json:"details,omitempty"`
+	RequestID string               `json:"request_id"`
+}
+
+// writeError maps err to a status, error code and fixed public message. Messages of
+// wrapped errors may describe internals or identify employees, so the chain is only
+// logged with the request ID; validation errors report their field errors instead.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var body apiErrorBody
+	var status int
+	var verr *service.ValidationError
+
+	switch {
+	case errors.As(err, &verr):
+		status, body.Code, body.Message, body.Details = http.StatusBadRequest, "validation_failed", "validation failed", verr.Errors
+	case errors.Is(err, service.ErrInvalidRequest):
+		status, body.Code, body.Message = http.StatusBadRequest, "invalid_request", "invalid request"
+	case errors.Is(err, service.ErrPermissionDenied):
+		status, body.Code, body.Message = http.StatusForbidden, "forbidden", "permission denied"
+	case errors.Is(err, repository.ErrNotFound):
+		status, body.Code, body.Message = http.StatusNotFound, "not_found", "not found"
+	case errors.Is(err, repository.ErrConflict):
+		status, body.Code, body.Message = http.StatusConflict, "conflict", "conflicts with the current state"
+	case errors.Is(err, service.ErrExportTooLarge):
+		status, body.Code, body.Message = http.StatusRequestEntityTooLarge, "export_too_large", "export too large; request it with async"
+	case errors.Is(err, service.ErrExportSuppressed):
+		status, body.Code, body.Message = http.StatusUnprocessableEntity, "export_suppressed", "too few responses selected to export"
+	case errors.Is(err, service.ErrResponsesSuppressed):
+		status, body.Code, body.Message = http.StatusUnprocessableEntity, "responses_suppressed", "too few responses selected to release"
+	default:
+		status, body.Code, body.Message = http.StatusInternalServerError, "internal", "internal server error"
+	}
+
+	log.Printf("Request failed [%s] %s %s: %d %s: %v", requestID(r.Context()), r.Method, r.URL.Path, status, body.Code, err)
+	writeAPIError(w, r, status, body)
+}
+
+// writeErasureDisabled reports that erasure is not configured on this server
+func writeErasureDisabled(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, r, http.StatusServiceUnavailable, apiErrorBody{Code: "erasure_disabled", Message: "erasure is not configured"})
+}
+
+// writeInvalidBody reports a request body that is not valid JSON for the endpoint
+func writeInvalidBody(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, r, http.StatusBadRequest, apiErrorBody{Code: "invalid_body", Message: "invalid request body"})
+}
+
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, body apiErrorBody) {
+	body.RequestID = requestID(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: body})
+}
+
+// withPermissions grants the permissions listed in X-Permissions when the request is
+// trusted: it carries the gateway token, or trustHeader is set for development
+func withPermissions(gatewayToken string, trustHeader bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trusted := trustHeader
+			if token := r.Header.Get("X-Gateway-Token"); gatewayToken != "" && token != "" {
+				trusted = trusted || subtle.ConstantTimeCompare([]byte(token), []byte(gatewayToken)) == 1
+			}
+
+			var perms []service.Permission
+			if trusted {
+				perms = service.ParsePermissions(r.Header.Get("X-Permissions"))
+			}
+			next.ServeHTTP(w, r.WithContext(service.WithPermissions(r.Context(), perms...)))
+		})
+	}
+}
+
+type requestIDKey struct{}
+
+// withRequestID tags each request with the caller's X-Request-ID, or a new one, and
+// echoes it in the response so errors can be matched to server logs
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// maxRequestIDLength bounds caller-supplied request IDs, which end up in logs
+const maxRequestIDLength = 128
+
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
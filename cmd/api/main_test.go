@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dashboard-case-study/pkg/repository"
+	"dashboard-case-study/pkg/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriteError tests the status, code and public message of every error kind, and
+// that wrapped messages never reach the client
+func TestWriteError(t *testing.T) {
+	verr := &service.ValidationError{}
+	verr.Add("employee_id", "unknown", "no employee has this ID")
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"validation", fmt.Errorf("failed to capture snapshot: %w", verr), http.StatusBadRequest, "validation_failed", "validation failed"},
+		{"invalid request", fmt.Errorf("%w: invalid filter mode: X", service.ErrInvalidRequest), http.StatusBadRequest, "invalid_request", "invalid request"},
+		{"forbidden", fmt.Errorf("%w: %s", service.ErrPermissionDenied, service.PermissionViewIdentifiers), http.StatusForbidden, "forbidden", "permission denied"},
+		{"not found", fmt.Errorf("employee %w: emp_secret", repository.ErrNotFound), http.StatusNotFound, "not_found", "not found"},
+		{"conflict", fmt.Errorf("%w: survey_secret", service.ErrSurveyNotLaunched), http.StatusConflict, "conflict", "conflicts with the current state"},
+		{"export too large", fmt.Errorf("%w: limit %d", service.ErrExportTooLarge, service.MaxSyncExportRows), http.StatusRequestEntityTooLarge, "export_too_large", "export too large; request it with async"},
+		{"export suppressed", service.ErrExportSuppressed, http.StatusUnprocessableEntity, "export_suppressed", "too few responses selected to export"},
+		{"responses suppressed", service.ErrResponsesSuppressed, http.StatusUnprocessableEntity, "responses_suppressed", "too few responses selected to release"},
+		{"internal", errors.New("pq: relation emp_secret does not exist"), http.StatusInternalServerError, "internal", "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, tt.err)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/test", nil)
+			req.Header.Set("X-Request-ID", "req_1")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, "req_1", rec.Header().Get("X-Request-ID"))
+			assert.NotContains(t, rec.Body.String(), "secret")
+
+			var body apiError
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Error.Code)
+			assert.Equal(t, tt.message, body.Error.Message)
+			assert.Equal(t, "req_1", body.Error.RequestID)
+			if tt.code == "validation_failed" {
+				assert.Equal(t, verr.Errors, body.Error.Details)
+			} else {
+				assert.Empty(t, body.Error.Details)
+			}
+		})
+	}
+}
+
+// TestWriteAPIErrorRequestID tests that a request without an ID gets a generated one,
+// echoed in both the header and the envelope
+func TestWriteAPIErrorRequestID(t *testing.T) {
+	handler := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeInvalidBody(w, r)
+	}))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/responses", nil))
+
+	var body apiError
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_body", body.Error.Code)
+	assert.NotEmpty(t, body.Error.RequestID)
+	assert.Equal(t, rec.Header().Get("X-Request-ID"), body.Error.RequestID)
+}
+
+// TestWriteErasureDisabled tests the error reported when no digest key is configured
+func TestWriteErasureDisabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	writeErasureDisabled(rec, httptest.NewRequest(http.MethodGet, "/api/v1/erasure-receipts/rc_1", nil))
+
+	var body apiError
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "erasure_disabled", body.Error.Code)
+}
@@ -0,0 +1,6 @@
+-- Migration: 002_snapshot_bands.up.sql
+-- Description: Index derived demographic bands captured in snapshot_core (v1.1)
+
+-- Expression indexes for band group-bys and filters
+CREATE INDEX idx_responses_age_band ON survey_responses((snapshot_core->>'age_band'));
+CREATE INDEX idx_responses_tenure_band ON survey_responses((snapshot_core->>'tenure_band'));
@@ -0,0 +1,9 @@
+-- Migration: 003_strip_snapshot_identifiers.up.sql
+-- Description: Remove direct identifiers from existing snapshots (snapshot v1.2 no longer stores them)
+
+UPDATE survey_responses
+SET snapshot_core = snapshot_core - 'employee_name' - 'employee_email'
+WHERE snapshot_core ?| ARRAY['employee_name', 'employee_email'];
+
+-- Pseudonyms are per-tenant HMACs; index them for respondent-level joins without raw IDs
+CREATE INDEX idx_responses_pseudonym ON survey_responses((snapshot_core->>'employee_pseudonym'));
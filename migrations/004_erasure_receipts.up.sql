@@ -0,0 +1,24 @@
+-- Migration: 004_erasure_receipts.up.sql
+-- Description: Audit trail for right-to-erasure requests
+
+-- ERASURE_RECEIPTS TABLE (No employee ID stored, only a recomputable digest)
+CREATE TABLE erasure_receipts (
+    receipt_id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    subject_digest VARCHAR(64) NOT NULL, -- HMAC-SHA256 of tenant_id || ':' || employee_id under a server-side key
+    requested_by VARCHAR(255) NOT NULL,
+    reason TEXT,
+    employees_deleted INTEGER NOT NULL,
+    history_rows_deleted INTEGER NOT NULL,
+    responses_anonymised INTEGER NOT NULL,
+    views_refreshed_at TIMESTAMP, -- NULL = refresh pending
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_erasure_receipts_tenant ON erasure_receipts(tenant_id, created_at DESC);
+CREATE INDEX idx_erasure_receipts_subject ON erasure_receipts(subject_digest);
+
+ALTER TABLE erasure_receipts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_erasure_receipts ON erasure_receipts
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
@@ -0,0 +1,28 @@
+-- Migration: 005_idempotent_responses.up.sql
+-- Description: Survey settings and duplicate-submission protection
+
+-- SURVEYS TABLE (Survey-level settings)
+CREATE TABLE surveys (
+    survey_id VARCHAR(255) PRIMARY KEY,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    single_response BOOLEAN NOT NULL DEFAULT FALSE, -- One response per employee
+    tenant_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_surveys_tenant ON surveys(tenant_id);
+
+ALTER TABLE surveys ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_surveys ON surveys
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+-- Client retry keys (Idempotency-Key header) and single-response flag copied from the survey
+ALTER TABLE survey_responses ADD COLUMN idempotency_key VARCHAR(255);
+ALTER TABLE survey_responses ADD COLUMN one_per_employee BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX idx_responses_idempotency_key ON survey_responses(tenant_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
+CREATE UNIQUE INDEX idx_responses_one_per_employee ON survey_responses(tenant_id, survey_id, employee_id)
+    WHERE one_per_employee;
@@ -0,0 +1,55 @@
+-- Migration: 006_response_versions.up.sql
+-- Description: Response edits, withdrawal and version history
+
+ALTER TABLE survey_responses ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE'
+    CHECK (status IN ('ACTIVE', 'WITHDRAWN', 'VOIDED'));
+ALTER TABLE survey_responses ADD COLUMN status_reason TEXT;
+ALTER TABLE survey_responses ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE survey_responses ADD COLUMN updated_at TIMESTAMP;
+
+-- Survey policy: recapture the snapshot when a response is edited
+ALTER TABLE surveys ADD COLUMN resnapshot_on_edit BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- SURVEY_RESPONSE_VERSIONS TABLE (Superseded revisions, append-only)
+CREATE TABLE survey_response_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    response_id VARCHAR(255) NOT NULL,
+    revision INTEGER NOT NULL,
+    answers JSONB NOT NULL,
+    snapshot_core JSONB NOT NULL,
+    version_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(255) NOT NULL, -- Who superseded this revision
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    tenant_id VARCHAR(255) NOT NULL,
+    UNIQUE (response_id, revision)
+);
+
+CREATE INDEX idx_response_versions_tenant ON survey_response_versions(tenant_id);
+
+ALTER TABLE survey_response_versions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_response_versions ON survey_response_versions
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+-- A withdrawn response no longer blocks resubmission to a single-response survey
+DROP INDEX idx_responses_one_per_employee;
+CREATE UNIQUE INDEX idx_responses_one_per_employee ON survey_responses(tenant_id, survey_id, employee_id)
+    WHERE one_per_employee AND status = 'ACTIVE';
+
+-- Dashboard aggregations only count active responses
+DROP MATERIALIZED VIEW mv_department_summary;
+
+CREATE MATERIALIZED VIEW mv_department_summary AS
+SELECT 
+    tenant_id,
+    survey_id,
+    snapshot_core->>'department' as department,
+    snapshot_core->>'performance_grade' as grade,
+    DATE_TRUNC('month', submitted_at) as month,
+    COUNT(*) as response_count
+FROM survey_responses
+WHERE status = 'ACTIVE'
+GROUP BY tenant_id, survey_id, department, grade, month;
+
+CREATE UNIQUE INDEX idx_mv_dept_summary ON mv_department_summary(tenant_id, survey_id, department, grade, month);
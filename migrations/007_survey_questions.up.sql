@@ -0,0 +1,5 @@
+-- Migration: 007_survey_questions.up.sql
+-- Description: Question definitions used to validate submitted answers
+
+-- Array of {question_id, text, type, required, min, max, options, max_length}
+ALTER TABLE surveys ADD COLUMN questions JSONB NOT NULL DEFAULT '[]';
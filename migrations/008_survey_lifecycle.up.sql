@@ -0,0 +1,30 @@
+-- Migration: 008_survey_lifecycle.up.sql
+-- Description: Survey states, response windows and eligible population
+
+-- Existing surveys keep accepting responses; new surveys start as drafts
+ALTER TABLE surveys ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'OPEN'
+    CHECK (status IN ('DRAFT', 'OPEN', 'CLOSED', 'ARCHIVED'));
+ALTER TABLE surveys ALTER COLUMN status SET DEFAULT 'DRAFT';
+ALTER TABLE surveys ADD COLUMN opens_at TIMESTAMP;  -- NULL = as soon as OPEN
+ALTER TABLE surveys ADD COLUMN closes_at TIMESTAMP; -- NULL = until CLOSED
+ALTER TABLE surveys ADD COLUMN audience JSONB NOT NULL DEFAULT '{}'; -- {employee_ids, unit_ids}
+ALTER TABLE surveys ADD COLUMN launched_at TIMESTAMP; -- NULL = legacy survey, no captured population
+
+-- SURVEY_ELIGIBILITY TABLE (Population frozen at launch)
+CREATE TABLE survey_eligibility (
+    survey_id VARCHAR(255) NOT NULL,
+    employee_id VARCHAR(255) NOT NULL,
+    unit_id VARCHAR(255), -- Unit at launch time
+    unit_path LTREE,      -- Unit path at launch time
+    tenant_id VARCHAR(255) NOT NULL,
+    captured_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (survey_id, employee_id)
+);
+
+CREATE INDEX idx_survey_eligibility_tenant ON survey_eligibility(tenant_id, survey_id);
+CREATE INDEX idx_survey_eligibility_path ON survey_eligibility USING GIST(unit_path);
+
+ALTER TABLE survey_eligibility ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_survey_eligibility ON survey_eligibility
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
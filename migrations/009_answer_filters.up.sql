@@ -0,0 +1,24 @@
+-- Migration: 009_answer_filters.up.sql
+-- Description: Indexes for filtering and grouping on answers keys
+
+-- Equality and multi-choice containment filters are rendered as answers @> {...}
+CREATE INDEX idx_responses_answers_gin ON survey_responses USING GIN(answers jsonb_path_ops);
+
+-- Numeric view of one answer; NULL unless the answer is a JSON number, so the cast never fails.
+-- IMMUTABLE so it can back expression indexes for range filters such as "q3 <= 2".
+CREATE OR REPLACE FUNCTION answer_numeric(answers JSONB, question_id TEXT)
+RETURNS NUMERIC AS $$
+    SELECT CASE WHEN jsonb_typeof(answers->question_id) = 'number'
+                THEN (answers->>question_id)::numeric END
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+-- Question IDs are tenant data, so range indexes are created per question on demand, e.g.
+--   SELECT create_answer_index('q3');
+CREATE OR REPLACE FUNCTION create_answer_index(question_id TEXT)
+RETURNS VOID AS $$
+BEGIN
+    EXECUTE format(
+        'CREATE INDEX IF NOT EXISTS %I ON survey_responses (tenant_id, answer_numeric(answers, %L))',
+        'idx_responses_answer_' || md5(question_id), question_id);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+-- Migration: 010_comment_search.up.sql
+-- Description: Full-text search over free-text answers
+
+-- Indexes every string value in answers; comment search narrows to one question afterwards
+CREATE INDEX idx_responses_answers_fts ON survey_responses
+    USING GIN(jsonb_to_tsvector('english', answers, '["string"]'));
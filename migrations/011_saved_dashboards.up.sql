@@ -0,0 +1,19 @@
+-- Migration: 011_saved_dashboards.up.sql
+-- Description: Persisted dashboard definitions
+
+-- DASHBOARDS TABLE (Widgets hold the stored DashboardQuery of each visualisation)
+CREATE TABLE dashboards (
+    dashboard_id VARCHAR(255) PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    widgets JSONB NOT NULL DEFAULT '[]', -- Array of {widget_id, title, query}
+    tenant_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_dashboards_tenant ON dashboards(tenant_id);
+
+ALTER TABLE dashboards ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_dashboards ON dashboards
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
@@ -0,0 +1,31 @@
+-- Migration: 012_view_refreshes.up.sql
+-- Description: Record materialized view refreshes so dashboards can report data freshness
+
+-- VIEW_REFRESHES TABLE (One row per materialized view, overwritten on each refresh)
+CREATE TABLE view_refreshes (
+    view_name VARCHAR(255) PRIMARY KEY,
+    data_as_of TIMESTAMP NOT NULL,   -- Snapshot time: changes committed before it are included
+    refreshed_at TIMESTAMP NOT NULL, -- Completion time
+    duration_ms INTEGER NOT NULL
+);
+
+-- Every caller (scheduler, erasure) records the refresh through the same function
+CREATE OR REPLACE FUNCTION refresh_dashboard_views()
+RETURNS void AS $$
+DECLARE
+    started TIMESTAMP := clock_timestamp();
+BEGIN
+    REFRESH MATERIALIZED VIEW CONCURRENTLY mv_department_summary;
+
+    INSERT INTO view_refreshes (view_name, data_as_of, refreshed_at, duration_ms)
+    VALUES ('mv_department_summary', NOW(), clock_timestamp(),
+            EXTRACT(MILLISECONDS FROM clock_timestamp() - started)::int)
+    ON CONFLICT (view_name) DO UPDATE
+    SET data_as_of = EXCLUDED.data_as_of,
+        refreshed_at = EXCLUDED.refreshed_at,
+        duration_ms = EXCLUDED.duration_ms;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Burst detection counts recent changes to responses
+CREATE INDEX idx_responses_changed_at ON survey_responses((COALESCE(updated_at, submitted_at)));
@@ -0,0 +1,30 @@
+-- Migration: 013_response_rollups.up.sql
+-- Description: Incrementally maintained response counts, updated in the writing transaction
+
+-- RESPONSE_ROLLUPS TABLE (Active responses per tenant, survey, unit, grade and month)
+CREATE TABLE response_rollups (
+    tenant_id VARCHAR(255) NOT NULL,
+    survey_id VARCHAR(255) NOT NULL,
+    unit_id VARCHAR(255) NOT NULL,   -- '' when the snapshot has no unit
+    grade VARCHAR(50) NOT NULL,      -- '' when the snapshot has no grade
+    month DATE NOT NULL,             -- UTC month of submitted_at
+    response_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, survey_id, unit_id, grade, month)
+);
+
+ALTER TABLE response_rollups ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_response_rollups ON response_rollups
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+-- Backfill from existing responses
+INSERT INTO response_rollups (tenant_id, survey_id, unit_id, grade, month, response_count)
+SELECT tenant_id, survey_id,
+       COALESCE(snapshot_core->>'unit_id', ''),
+       COALESCE(snapshot_core->>'performance_grade', ''),
+       DATE_TRUNC('month', submitted_at)::date,
+       COUNT(*)
+FROM survey_responses
+WHERE status = 'ACTIVE'
+GROUP BY 1, 2, 3, 4, 5;
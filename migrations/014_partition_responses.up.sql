@@ -0,0 +1,156 @@
+-- Migration: 014_partition_responses.up.sql
+-- Description: Partition survey_responses monthly by submitted_at
+--
+-- Unique indexes on a partitioned table must include the partition key, so the
+-- idempotency-key and one-response-per-employee guarantees move to claim tables
+-- maintained by triggers. The claim constraints keep the old index names, which the
+-- application maps to its duplicate-submission errors.
+
+ALTER TABLE survey_responses RENAME TO survey_responses_unpartitioned;
+
+-- The materialized view depends on the table and is recreated below
+DROP MATERIALIZED VIEW mv_department_summary;
+
+CREATE TABLE survey_responses (
+    LIKE survey_responses_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS,
+    PRIMARY KEY (response_id, submitted_at)
+) PARTITION BY RANGE (submitted_at);
+
+-- One partition per month from the oldest response to three months ahead; the
+-- partition manager keeps creating future months from here on
+DO $$
+DECLARE
+    m DATE := DATE_TRUNC('month', COALESCE((SELECT MIN(submitted_at) FROM survey_responses_unpartitioned), NOW()));
+BEGIN
+    WHILE m <= DATE_TRUNC('month', NOW() + INTERVAL '3 months') LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF survey_responses FOR VALUES FROM (%L) TO (%L)',
+            'survey_responses_p' || to_char(m, 'YYYYMM'), m, m + INTERVAL '1 month');
+        m := m + INTERVAL '1 month';
+    END LOOP;
+END;
+$$;
+
+INSERT INTO survey_responses SELECT * FROM survey_responses_unpartitioned;
+DROP TABLE survey_responses_unpartitioned;
+
+-- Indexes on the parent cascade to every current, future and attached partition.
+-- The primary key leads with response_id, so it also serves lookups by ID.
+CREATE INDEX idx_responses_tenant_survey ON survey_responses(tenant_id, survey_id, submitted_at DESC);
+CREATE INDEX idx_responses_employee ON survey_responses(employee_id);
+CREATE INDEX idx_responses_snapshot_gin ON survey_responses USING GIN(snapshot_core);
+CREATE INDEX idx_responses_department ON survey_responses((snapshot_core->>'department'));
+CREATE INDEX idx_responses_grade ON survey_responses((snapshot_core->>'performance_grade'));
+CREATE INDEX idx_responses_unit_id ON survey_responses((snapshot_core->>'unit_id'));
+CREATE INDEX idx_responses_age_band ON survey_responses((snapshot_core->>'age_band'));
+CREATE INDEX idx_responses_tenure_band ON survey_responses((snapshot_core->>'tenure_band'));
+CREATE INDEX idx_responses_pseudonym ON survey_responses((snapshot_core->>'employee_pseudonym'));
+CREATE INDEX idx_responses_idempotency_lookup ON survey_responses(tenant_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
+CREATE INDEX idx_responses_answers_gin ON survey_responses USING GIN(answers jsonb_path_ops);
+CREATE INDEX idx_responses_answers_fts ON survey_responses
+    USING GIN(jsonb_to_tsvector('english', answers, '["string"]'));
+CREATE INDEX idx_responses_changed_at ON survey_responses((COALESCE(updated_at, submitted_at)));
+
+ALTER TABLE survey_responses ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_responses ON survey_responses
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+-- CLAIM TABLES (Global uniqueness across partitions)
+CREATE TABLE response_idempotency_keys (
+    tenant_id VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    response_id VARCHAR(255) NOT NULL,
+    CONSTRAINT idx_responses_idempotency_key PRIMARY KEY (tenant_id, idempotency_key)
+);
+
+CREATE TABLE response_employee_claims (
+    tenant_id VARCHAR(255) NOT NULL,
+    survey_id VARCHAR(255) NOT NULL,
+    employee_id VARCHAR(255) NOT NULL,
+    response_id VARCHAR(255) NOT NULL,
+    CONSTRAINT idx_responses_one_per_employee PRIMARY KEY (tenant_id, survey_id, employee_id)
+);
+
+ALTER TABLE response_idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE response_employee_claims ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_idempotency_keys ON response_idempotency_keys
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+CREATE POLICY tenant_isolation_employee_claims ON response_employee_claims
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+INSERT INTO response_idempotency_keys (tenant_id, idempotency_key, response_id)
+SELECT tenant_id, idempotency_key, response_id
+FROM survey_responses
+WHERE idempotency_key IS NOT NULL;
+
+INSERT INTO response_employee_claims (tenant_id, survey_id, employee_id, response_id)
+SELECT tenant_id, survey_id, employee_id, response_id
+FROM survey_responses
+WHERE one_per_employee AND status = 'ACTIVE';
+
+-- An active single-response answer holds its claim; withdrawing, voiding or erasing
+-- the respondent releases or renames it
+CREATE OR REPLACE FUNCTION maintain_response_claims()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.idempotency_key IS NOT NULL THEN
+        INSERT INTO response_idempotency_keys (tenant_id, idempotency_key, response_id)
+        VALUES (NEW.tenant_id, NEW.idempotency_key, NEW.response_id);
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        DELETE FROM response_employee_claims
+        WHERE tenant_id = OLD.tenant_id
+          AND survey_id = OLD.survey_id
+          AND employee_id = OLD.employee_id
+          AND response_id = OLD.response_id;
+    END IF;
+
+    IF NEW.one_per_employee AND NEW.status = 'ACTIVE' THEN
+        INSERT INTO response_employee_claims (tenant_id, survey_id, employee_id, response_id)
+        VALUES (NEW.tenant_id, NEW.survey_id, NEW.employee_id, NEW.response_id);
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_response_claims_insert
+    AFTER INSERT ON survey_responses
+    FOR EACH ROW EXECUTE FUNCTION maintain_response_claims();
+
+CREATE TRIGGER trg_response_claims_update
+    AFTER UPDATE OF status, employee_id ON survey_responses
+    FOR EACH ROW EXECUTE FUNCTION maintain_response_claims();
+
+CREATE OR REPLACE FUNCTION release_response_claims()
+RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM response_idempotency_keys WHERE tenant_id = OLD.tenant_id AND response_id = OLD.response_id;
+    DELETE FROM response_employee_claims WHERE tenant_id = OLD.tenant_id AND response_id = OLD.response_id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_response_claims_delete
+    AFTER DELETE ON survey_responses
+    FOR EACH ROW EXECUTE FUNCTION release_response_claims();
+
+-- Recreated unchanged from 006
+CREATE MATERIALIZED VIEW mv_department_summary AS
+SELECT 
+    tenant_id,
+    survey_id,
+    snapshot_core->>'department' as department,
+    snapshot_core->>'performance_grade' as grade,
+    DATE_TRUNC('month', submitted_at) as month,
+    COUNT(*) as response_count
+FROM survey_responses
+WHERE status = 'ACTIVE'
+GROUP BY tenant_id, survey_id, department, grade, month;
+
+CREATE UNIQUE INDEX idx_mv_dept_summary ON mv_department_summary(tenant_id, survey_id, department, grade, month);
@@ -0,0 +1,42 @@
+-- Migration: 015_export_jobs.up.sql
+-- Description: Asynchronous dashboard exports and their downloadable results
+
+CREATE TABLE export_jobs (
+    job_id VARCHAR(255) PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'RUNNING'
+        CHECK (status IN ('RUNNING', 'SUCCEEDED', 'FAILED')),
+    format VARCHAR(10) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    request JSONB NOT NULL,       -- The ExportRequest the job runs
+    identified BOOLEAN NOT NULL,  -- Written with identifiers and free text; erasure deletes it
+    row_count INTEGER,            -- Data rows written, header excluded
+    error TEXT,                   -- Set when FAILED
+    tenant_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL -- Hidden from then on and deleted by the next purge
+);
+
+CREATE INDEX idx_export_jobs_tenant ON export_jobs(tenant_id, created_at DESC);
+CREATE INDEX idx_export_jobs_expiry ON export_jobs(tenant_id, expires_at);
+
+-- The file is streamed in as it is written, so no export is ever held whole in memory
+CREATE TABLE export_chunks (
+    job_id VARCHAR(255) NOT NULL REFERENCES export_jobs(job_id) ON DELETE CASCADE,
+    seq INTEGER NOT NULL,
+    data BYTEA NOT NULL,
+    tenant_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (job_id, seq)
+);
+
+ALTER TABLE export_jobs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE export_chunks ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_export_jobs ON export_jobs
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+CREATE POLICY tenant_isolation_export_chunks ON export_chunks
+    USING (tenant_id = current_setting('app.tenant_id', TRUE));
+
+-- Erasure receipts record how many identified exports an erasure deleted
+ALTER TABLE erasure_receipts ADD COLUMN exports_deleted INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Migration: 016_current_unit_names.up.sql
+-- Description: Look up today's org units by name, where CURRENT department filters start
+
+CREATE INDEX idx_org_units_current_name ON org_units_history(tenant_id, unit_name)
+    WHERE valid_to IS NULL;
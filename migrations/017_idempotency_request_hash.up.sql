@@ -0,0 +1,6 @@
+-- Migration: 017_idempotency_request_hash.up.sql
+-- Description: Fingerprint the submission stored under each idempotency key, so a key
+-- reused with a different body is rejected instead of replayed
+
+-- The claim table is not partitioned, so detached partitions keep matching the parent
+ALTER TABLE response_idempotency_keys ADD COLUMN request_hash VARCHAR(64); -- NULL for keys stored before this migration
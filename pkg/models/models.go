@@ -23,6 +23,28 @@ const (
 	MappingTypeSplit  MappingType = "SPLIT"  // 1:N (one → multiple)
 )
 
+// IdentifierPolicy defines how direct identifiers are handled in snapshot_core
+type IdentifierPolicy string
+
+const (
+	IdentifierPolicyExclude      IdentifierPolicy = "EXCLUDE"      // Drop name/email entirely
+	IdentifierPolicyPseudonymise IdentifierPolicy = "PSEUDONYMISE" // Store keyed HMAC of employee ID
+)
+
+// SnapshotPrivacyPolicy controls which personal data is copied into snapshots
+type SnapshotPrivacyPolicy struct {
+	Identifiers IdentifierPolicy `json:"identifiers"`
+}
+
+// ResponseStatus defines the lifecycle state of a response
+type ResponseStatus string
+
+const (
+	ResponseStatusActive    ResponseStatus = "ACTIVE"
+	ResponseStatusWithdrawn ResponseStatus = "WITHDRAWN" // Retracted by the respondent
+	ResponseStatusVoided    ResponseStatus = "VOIDED"    // Invalidated by an admin
+)
+
 // Response represents a survey response with snapshot
 type Response struct {
 	ResponseID   string                 `json:"response_id" db:"response_id"`
@@ -34,6 +56,128 @@ type Response struct {
 	Answers      json.RawMessage        `json:"answers" db:"answers"`
 	TenantID     string                 `json:"tenant_id" db:"tenant_id"`
 	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+	Status       ResponseStatus         `json:"status" db:"status"`
+	Revision     int                    `json:"revision" db:"revision"` // Starts at 1, +1 per edit
+	UpdatedAt    *time.Time             `json:"updated_at,omitempty" db:"updated_at"`
+
+	IdempotencyKey string `json:"-" db:"idempotency_key"`  // Client retry key, NULL if not sent
+	RequestHash    string `json:"-" db:"request_hash"`     // Fingerprint of the submission stored under IdempotencyKey
+	OnePerEmployee bool   `json:"-" db:"one_per_employee"` // Survey allows a single response
+}
+
+// ResponseVersion is an archived revision of a response
+type ResponseVersion struct {
+	ResponseID   string                 `json:"response_id" db:"response_id"`
+	Revision     int                    `json:"revision" db:"revision"`
+	Answers      json.RawMessage        `json:"answers" db:"answers"`
+	SnapshotCore map[string]interface{} `json:"snapshot_core" db:"snapshot_core"`
+	VersionID    string                 `json:"version_id" db:"version_id"`
+	Status       ResponseStatus         `json:"status" db:"status"`
+	ChangedBy    string                 `json:"changed_by" db:"changed_by"` // Who superseded this revision
+	ChangedAt    time.Time              `json:"changed_at" db:"changed_at"`
+}
+
+// QuestionType defines how an answer is captured and validated
+type QuestionType string
+
+const (
+	QuestionTypeLikert       QuestionType = "LIKERT"        // Integer on a Min..Max scale (default 1..5)
+	QuestionTypeNPS          QuestionType = "NPS"           // Integer 0..10
+	QuestionTypeSingleChoice QuestionType = "SINGLE_CHOICE" // One of Options
+	QuestionTypeMultiChoice  QuestionType = "MULTI_CHOICE"  // Subset of Options
+	QuestionTypeFreeText     QuestionType = "FREE_TEXT"     // String up to MaxLength
+)
+
+// Question is one item of a survey definition
+type Question struct {
+	QuestionID string       `json:"question_id"`
+	Text       string       `json:"text"`
+	Type       QuestionType `json:"type"`
+	Required   bool         `json:"required"`
+	Min        *int         `json:"min,omitempty"`        // Likert lower bound
+	Max        *int         `json:"max,omitempty"`        // Likert upper bound
+	Options    []string     `json:"options,omitempty"`    // Choice questions
+	MaxLength  int          `json:"max_length,omitempty"` // Free text, 0 = default limit
+}
+
+// SurveyStatus defines the lifecycle state of a survey
+type SurveyStatus string
+
+const (
+	SurveyStatusDraft    SurveyStatus = "DRAFT"    // Being designed, no responses
+	SurveyStatusOpen     SurveyStatus = "OPEN"     // Accepting responses within the window
+	SurveyStatusClosed   SurveyStatus = "CLOSED"   // No longer accepting responses
+	SurveyStatusArchived SurveyStatus = "ARCHIVED" // Read-only, hidden from active lists
+)
+
+// SurveyAudience defines who is invited; resolved to a fixed population at launch
+type SurveyAudience struct {
+	EmployeeIDs []string `json:"employee_ids,omitempty"`
+	UnitIDs     []string `json:"unit_ids,omitempty"` // Whole org subtrees under these units
+}
+
+// Survey represents a survey definition and its settings
+type Survey struct {
+	SurveyID         string         `json:"survey_id" db:"survey_id"`
+	Title            string         `json:"title" db:"title"`
+	Questions        []Question     `json:"questions" db:"questions"`                   // JSONB; empty = answers not validated
+	SingleResponse   bool           `json:"single_response" db:"single_response"`       // One response per employee
+	ResnapshotOnEdit bool           `json:"resnapshot_on_edit" db:"resnapshot_on_edit"` // Edits recapture the snapshot
+	Status           SurveyStatus   `json:"status" db:"status"`
+	OpensAt          *time.Time     `json:"opens_at,omitempty" db:"opens_at"`       // NULL = as soon as OPEN
+	ClosesAt         *time.Time     `json:"closes_at,omitempty" db:"closes_at"`     // NULL = until CLOSED
+	Audience         SurveyAudience `json:"audience" db:"audience"`                 // JSONB
+	LaunchedAt       *time.Time     `json:"launched_at,omitempty" db:"launched_at"` // NULL = legacy, no population
+	TenantID         string         `json:"tenant_id" db:"tenant_id"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Participation summarises response rate against the eligible population
+type Participation struct {
+	SurveyID  string  `json:"survey_id"`
+	Invited   int     `json:"invited"`
+	Responded int     `json:"responded"`
+	Rate      float64 `json:"rate"` // Responded / Invited, 0..1
+}
+
+// UnitResponseCount is the raw participation of one unit as captured at launch
+type UnitResponseCount struct {
+	UnitID    string `json:"unit_id"`
+	Invited   int    `json:"invited"`
+	Responded int    `json:"responded"`
+}
+
+// ParticipationCounts holds participation figures; Responded and Rate are nil when suppressed
+type ParticipationCounts struct {
+	Invited    int      `json:"invited"`
+	Responded  *int     `json:"responded"`
+	Rate       *float64 `json:"rate"`
+	Suppressed bool     `json:"suppressed,omitempty"` // Below the anonymity threshold
+}
+
+// UnitParticipation reports participation for one org unit, alone and with its subtree
+type UnitParticipation struct {
+	UnitID       string              `json:"unit_id"`
+	UnitName     string              `json:"unit_name"`
+	ParentUnitID *string             `json:"parent_unit_id"`
+	Direct       ParticipationCounts `json:"direct"`             // Employees directly in the unit
+	Subtree      ParticipationCounts `json:"subtree"`            // Unit plus all descendants
+	Unmapped     bool                `json:"unmapped,omitempty"` // CURRENT mode: unit was split and cannot be attributed
+}
+
+// ParticipationResult is the response-rate breakdown of a survey by org unit
+type ParticipationResult struct {
+	SurveyID           string              `json:"survey_id"`
+	FilterMode         FilterMode          `json:"filter_mode"` // HISTORICAL = structure at launch, CURRENT = today's
+	Total              ParticipationCounts `json:"total"`
+	Units              []UnitParticipation `json:"units"`
+	AnonymityThreshold int                 `json:"anonymity_threshold"`
+}
+
+// SurveyStatusRequest represents API request to move a survey to another state
+type SurveyStatusRequest struct {
+	Status SurveyStatus `json:"status"`
 }
 
 // Employee represents current employee state
@@ -94,14 +238,131 @@ type Snapshot struct {
 	Timestamp    time.Time              `json:"timestamp"`
 }
 
+// Band is a labelled half-open range [Min, Max) used to bucket numeric attributes
+type Band struct {
+	Label string   `json:"label"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"` // nil = unbounded
+}
+
+// Contains reports whether value falls inside the band
+func (b Band) Contains(value float64) bool {
+	return value >= b.Min && (b.Max == nil || value < *b.Max)
+}
+
+// BandConfig holds the demographic band definitions applied at capture time
+type BandConfig struct {
+	AgeBands    []Band `json:"age_bands"`    // In years
+	TenureBands []Band `json:"tenure_bands"` // In years
+}
+
+// MetricType defines an engagement metric computed over an answer
+type MetricType string
+
+const (
+	MetricTypeENPS          MetricType = "ENPS"          // % promoters (9-10) minus % detractors (0-6)
+	MetricTypeFavourability MetricType = "FAVOURABILITY" // % favourable / neutral / unfavourable
+	MetricTypeMean          MetricType = "MEAN"          // Mean score
+)
+
+// MetricSpec requests a metric over one question's numeric answers
+type MetricSpec struct {
+	Type            MetricType `json:"type"`
+	QuestionID      string     `json:"question_id"`
+	FavourableMin   *float64   `json:"favourable_min,omitempty"`   // Favourability: default 4 (1-5 scale)
+	UnfavourableMax *float64   `json:"unfavourable_max,omitempty"` // Favourability: default 2 (1-5 scale)
+}
+
+// Thresholds returns the inclusive top and bottom cut-offs used to bucket answers
+func (m MetricSpec) Thresholds() (top, bottom float64) {
+	if m.Type == MetricTypeENPS {
+		return 9, 6
+	}
+
+	top, bottom = 4, 2
+	if m.FavourableMin != nil {
+		top = *m.FavourableMin
+	}
+	if m.UnfavourableMax != nil {
+		bottom = *m.UnfavourableMax
+	}
+	return top, bottom
+}
+
+// TimeBucket defines the granularity of trend queries
+type TimeBucket string
+
+const (
+	TimeBucketDay     TimeBucket = "day"
+	TimeBucketWeek    TimeBucket = "week" // ISO weeks, starting Monday
+	TimeBucketMonth   TimeBucket = "month"
+	TimeBucketQuarter TimeBucket = "quarter"
+)
+
+// Reserved group-by dimensions that are not snapshot_core keys
+const (
+	GroupByTimeBucket = "time_bucket" // Start date of the submitted_at bucket, YYYY-MM-DD
+	GroupBySurvey     = "survey_id"   // Survey wave
+)
+
+// AnswerFieldPrefix addresses answers keys in filters and group-bys, e.g. "answers.q3".
+// Unprefixed fields are snapshot_core keys.
+const AnswerFieldPrefix = "answers."
+
+// FilterOp is a comparison applied to an answer
+type FilterOp string
+
+const (
+	FilterOpEq       FilterOp = "eq"
+	FilterOpNe       FilterOp = "ne"
+	FilterOpLt       FilterOp = "lt"
+	FilterOpLte      FilterOp = "lte"
+	FilterOpGt       FilterOp = "gt"
+	FilterOpGte      FilterOp = "gte"
+	FilterOpIn       FilterOp = "in"       // Value is an array of candidate answers
+	FilterOpContains FilterOp = "contains" // Multi-choice answer includes Value
+)
+
+// AnswerFilter restricts responses by one answer. Equality compares JSON values, so 3 and
+// "3" differ; range operators require a number and only match numeric answers.
+type AnswerFilter struct {
+	QuestionID string      `json:"question_id"`
+	Op         FilterOp    `json:"op"`
+	Value      interface{} `json:"value"`
+}
+
 // DashboardQuery represents a dashboard filter request
 type DashboardQuery struct {
 	Filters    map[string]interface{} `json:"filters"`
 	FilterMode FilterMode             `json:"filter_mode"`
 	TimeRange  TimeRange              `json:"time_range"`
 	TenantID   string                 `json:"tenant_id"`
+	GroupBy    []string               `json:"group_by,omitempty"` // snapshot_core keys, e.g. "age_band"
+	Metrics    []MetricSpec           `json:"metrics,omitempty"`
+	TimeBucket TimeBucket             `json:"time_bucket,omitempty"` // Required when grouping by time_bucket
+	TimeZone   string                 `json:"time_zone,omitempty"`   // IANA zone for bucket boundaries, default UTC
+	SurveyID   string                 `json:"survey_id,omitempty"`   // Restrict to one survey
+
+	AnswerFilters []AnswerFilter `json:"answer_filters,omitempty"` // ANDed with Filters
+
+	IncludeWithdrawn bool             `json:"include_withdrawn,omitempty"` // Also count withdrawn/voided responses
+	Benchmarks       []BenchmarkScope `json:"benchmarks,omitempty"`        // Reference populations attached to each group
+
+	// UnitMapping maps historical unit IDs to current ones so CURRENT-mode group-bys
+	// on department/unit_id use today's structure. Set by the service, not by callers.
+	UnitMapping map[string]string `json:"-"`
+
+	Explain bool `json:"explain,omitempty"` // Resolve and plan the query without running it
+
+	// Archived holds responses restored from cold storage for archived months in the
+	// time range; they are queried together with the live table. Set by the service.
+	Archived []Response `json:"-"`
 }
 
+// SnapshotSchema maps each snapshot_core key in a tenant's responses to the JSON types
+// its values take, e.g. "string" or "number"
+type SnapshotSchema map[string][]string
+
 // TimeRange represents a date range
 type TimeRange struct {
 	From time.Time `json:"from"`
@@ -113,7 +374,311 @@ type DashboardResult struct {
 	Responses    []Response             `json:"responses"`
 	Count        int                    `json:"count"`
 	Aggregations map[string]interface{} `json:"aggregations,omitempty"`
+	Groups       []GroupResult          `json:"groups,omitempty"`
 	Provenance   *ProvenanceInfo        `json:"provenance,omitempty"`
+	Metadata     *QueryMetadata         `json:"metadata,omitempty"`
+	Explain      *QueryExplanation      `json:"explain,omitempty"` // Set instead of results in explain mode
+}
+
+// QueryExplanation shows how a dashboard query would be resolved and executed
+type QueryExplanation struct {
+	FilterMode        FilterMode         `json:"filter_mode"`
+	DepartmentMapping *DepartmentMapping `json:"department_mapping,omitempty"` // CURRENT department filter
+	Passes            []ExplainedPass    `json:"passes"`                       // HYBRID runs a historical and a current pass
+}
+
+// DepartmentMapping shows which historical units a current department filter selects
+type DepartmentMapping struct {
+	Department        string         `json:"department"`
+	HistoricalUnitIDs []string       `json:"historical_unit_ids"`
+	Chains            []MappingChain `json:"chains"`          // As walked back from the current units, one per historical unit
+	MappingApplied    bool           `json:"mapping_applied"` // False when no chain followed a restructure
+	Note              string         `json:"note,omitempty"`  // Why no mapping applied
+}
+
+// MappingChain is the path of restructures from a historical unit to today's unit
+type MappingChain struct {
+	HistoricalUnitID string           `json:"historical_unit_id"`
+	Steps            []OrgUnitMapping `json:"steps"` // Mappings followed, oldest first
+	CurrentUnitID    string           `json:"current_unit_id"`
+	Attributable     bool             `json:"attributable"` // False when a SPLIT ends the chain
+}
+
+// ExplainedPass is one execution of the query in a single filter mode
+type ExplainedPass struct {
+	Mode        FilterMode             `json:"mode"`
+	Filters     map[string]interface{} `json:"filters"`                // As executed, after org mapping
+	UnitMapping map[string]string      `json:"unit_mapping,omitempty"` // Historical → current unit for org group-bys
+	Path        QueryPath              `json:"path"`
+	Reason      string                 `json:"reason,omitempty"`
+	Statements  []ExplainedStatement   `json:"statements"`
+}
+
+// ExplainedStatement is a generated SQL statement with its Postgres plan
+type ExplainedStatement struct {
+	Purpose       string          `json:"purpose"` // responses, aggregate or summary
+	SQL           string          `json:"sql"`
+	Args          []interface{}   `json:"args"`
+	Plan          json.RawMessage `json:"plan"` // EXPLAIN (FORMAT JSON) output
+	EstimatedRows float64         `json:"estimated_rows"`
+	EstimatedCost float64         `json:"estimated_cost"`
+}
+
+// QueryPath names the storage a dashboard query was answered from
+type QueryPath string
+
+const (
+	QueryPathBaseTable        QueryPath = "base_table"        // survey_responses
+	QueryPathMaterializedView QueryPath = "materialized_view" // mv_department_summary; no response rows
+)
+
+// QueryMetadata describes how a dashboard query was executed
+type QueryMetadata struct {
+	Path   QueryPath `json:"path"`
+	Reason string    `json:"reason,omitempty"` // Why the materialized view was not used
+
+	DataAsOf *time.Time `json:"data_as_of,omitempty"` // Snapshot time of the view; nil for live data
+
+	ArchivedResponses int `json:"archived_responses,omitempty"` // Rows read from cold storage
+}
+
+// RollupMismatch is a rollup row whose count differs from survey_responses
+type RollupMismatch struct {
+	TenantID string    `json:"tenant_id"`
+	SurveyID string    `json:"survey_id"`
+	UnitID   string    `json:"unit_id"`
+	Grade    string    `json:"grade"`
+	Month    time.Time `json:"month"`
+	Expected int       `json:"expected"` // Counted from survey_responses
+	Actual   int       `json:"actual"`   // Stored in response_rollups
+}
+
+// RollupReport is the outcome of a rollup rebuild or verification
+type RollupReport struct {
+	RowsRebuilt int              `json:"rows_rebuilt"`
+	Mismatches  []RollupMismatch `json:"mismatches"`
+}
+
+// ResponsePartition is one monthly partition of survey_responses
+type ResponsePartition struct {
+	Name     string    `json:"name"`
+	Month    time.Time `json:"month"`    // First day of the month, UTC
+	Attached bool      `json:"attached"` // Detached partitions are standalone tables awaiting archival
+}
+
+// PartitionReport lists the partitions a maintenance run changed
+type PartitionReport struct {
+	Created  []string `json:"created"`
+	Detached []string `json:"detached"`
+}
+
+// ArchivedMonth reports one partition moved to cold storage
+type ArchivedMonth struct {
+	Partition string    `json:"partition"`
+	Month     time.Time `json:"month"`
+	Tenants   int       `json:"tenants"`   // One file per tenant
+	Responses int       `json:"responses"` // Rows written across all files
+}
+
+// ArchiveReport lists the partitions an archival run exported and dropped
+type ArchiveReport struct {
+	Months []ArchivedMonth `json:"months"`
+}
+
+// ViewRefresh records the last refresh of a materialized view
+type ViewRefresh struct {
+	ViewName    string    `json:"view_name" db:"view_name"`
+	DataAsOf    time.Time `json:"data_as_of" db:"data_as_of"` // Changes committed before this are included
+	RefreshedAt time.Time `json:"refreshed_at" db:"refreshed_at"`
+	DurationMS  int       `json:"duration_ms" db:"duration_ms"`
+}
+
+// GroupResult represents one row of a grouped aggregation
+type GroupResult struct {
+	Key        map[string]string `json:"key"`                  // group-by field → value
+	Count      int               `json:"count"`                // 0 when suppressed
+	Suppressed bool              `json:"suppressed,omitempty"` // Fewer responses than the anonymity threshold
+	Metrics    []MetricValue     `json:"metrics,omitempty"`    // Same order as DashboardQuery.Metrics
+
+	Benchmarks []Benchmark `json:"benchmarks,omitempty"` // Same order as DashboardQuery.Benchmarks
+}
+
+// BenchmarkScope selects the population a group is benchmarked against. Benchmarks use
+// the query's filters except department/unit_id, so a filtered unit still sees the company.
+type BenchmarkScope string
+
+const (
+	BenchmarkCompany    BenchmarkScope = "company"     // Every response in the tenant
+	BenchmarkParentUnit BenchmarkScope = "parent_unit" // The subtree of the group's parent unit
+)
+
+// Benchmark holds the metrics of a reference population for one group
+type Benchmark struct {
+	Scope      BenchmarkScope `json:"scope"`
+	UnitID     string         `json:"unit_id,omitempty"`    // Parent unit of parent_unit benchmarks; empty for root units
+	Count      int            `json:"count"`                // 0 when suppressed
+	Suppressed bool           `json:"suppressed,omitempty"` // Fewer responses than the anonymity threshold
+	Metrics    []MetricValue  `json:"metrics,omitempty"`
+}
+
+// MetricValue is a computed metric for one group, with its 95% confidence interval
+type MetricValue struct {
+	Type        MetricType         `json:"type"`
+	QuestionID  string             `json:"question_id"`
+	Respondents int                `json:"respondents"` // Numeric answers behind the value; 0 when suppressed
+	Value       *float64           `json:"value"`       // eNPS, % favourable or mean; nil when suppressed
+	CILow       *float64           `json:"ci_low"`
+	CIHigh      *float64           `json:"ci_high"`
+	Breakdown   map[string]float64 `json:"breakdown,omitempty"`  // Percentages per bucket
+	Suppressed  bool               `json:"suppressed,omitempty"` // Below the anonymity threshold
+
+	// Raw SQL aggregates the value is derived from
+	Mean   float64 `json:"-"`
+	StdDev float64 `json:"-"`
+	Top    int     `json:"-"` // Answers >= top threshold
+	Bottom int     `json:"-"` // Answers <= bottom threshold
+}
+
+// ComparisonPeriod selects one side of a comparison by time range, survey, or both
+type ComparisonPeriod struct {
+	Label     string     `json:"label,omitempty"`
+	TimeRange *TimeRange `json:"time_range,omitempty"` // Defaults to the base query's time range
+	SurveyID  string     `json:"survey_id,omitempty"`
+}
+
+// ComparisonQuery runs the same dashboard query over two periods
+type ComparisonQuery struct {
+	Query    DashboardQuery   `json:"query"`
+	Baseline ComparisonPeriod `json:"baseline"`
+	Current  ComparisonPeriod `json:"current"`
+}
+
+// ComparisonResult pairs per-group results of the baseline and current periods
+type ComparisonResult struct {
+	Baseline ComparisonPeriod  `json:"baseline"`
+	Current  ComparisonPeriod  `json:"current"`
+	Groups   []GroupComparison `json:"groups"`
+}
+
+// GroupComparison is one group present in either period
+type GroupComparison struct {
+	Key           map[string]string  `json:"key"`
+	BaselineCount int                `json:"baseline_count"`
+	CurrentCount  int                `json:"current_count"`
+	Metrics       []MetricComparison `json:"metrics,omitempty"` // Same order as DashboardQuery.Metrics
+}
+
+// MetricComparison is the change in one metric between the two periods
+type MetricComparison struct {
+	Type        MetricType   `json:"type"`
+	QuestionID  string       `json:"question_id"`
+	Baseline    *MetricValue `json:"baseline"` // nil when the group is absent from the period
+	Current     *MetricValue `json:"current"`
+	Delta       *float64     `json:"delta"`   // current - baseline; nil if either side is missing or suppressed
+	PValue      *float64     `json:"p_value"` // Two-sided z-test on the delta
+	Significant bool         `json:"significant"`
+}
+
+// CommentQuery requests free-text answers to one question for the responses a query selects
+type CommentQuery struct {
+	Query      DashboardQuery `json:"query"`
+	QuestionID string         `json:"question_id"`
+	Search     string         `json:"search,omitempty"` // Full-text search, websearch syntax
+	Limit      int            `json:"limit,omitempty"`
+}
+
+// CommentResult holds a shuffled sample of comments, detached from any respondent
+type CommentResult struct {
+	QuestionID  string   `json:"question_id"`
+	Respondents int      `json:"respondents"` // Non-empty answers in the group, before search; 0 when suppressed
+	Comments    []string `json:"comments"`
+	Suppressed  bool     `json:"suppressed,omitempty"` // Below the anonymity threshold
+}
+
+// Dashboard is a saved set of widgets, each running its own query
+type Dashboard struct {
+	DashboardID string    `json:"dashboard_id" db:"dashboard_id"`
+	Title       string    `json:"title" db:"title"`
+	Widgets     []Widget  `json:"widgets" db:"widgets"` // JSONB
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Widget is one visualisation on a saved dashboard
+type Widget struct {
+	WidgetID string         `json:"widget_id"`
+	Title    string         `json:"title"`
+	Query    DashboardQuery `json:"query"` // tenant_id is taken from the dashboard
+}
+
+// DashboardRunRequest holds runtime overrides applied to every widget of a dashboard
+type DashboardRunRequest struct {
+	Filters    map[string]interface{} `json:"filters,omitempty"` // Merged over each widget's filters
+	FilterMode FilterMode             `json:"filter_mode,omitempty"`
+	TimeRange  *TimeRange             `json:"time_range,omitempty"`
+}
+
+// DashboardRunResult holds the result of every widget of a dashboard
+type DashboardRunResult struct {
+	DashboardID string         `json:"dashboard_id"`
+	Widgets     []WidgetResult `json:"widgets"`
+}
+
+// WidgetResult is one widget's result; a failing widget does not fail the dashboard
+type WidgetResult struct {
+	WidgetID string           `json:"widget_id"`
+	Result   *DashboardResult `json:"result,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// ExportFormat is the file type of a dashboard export
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatXLSX ExportFormat = "xlsx"
+)
+
+// ExportKind selects what a dashboard export contains
+type ExportKind string
+
+const (
+	ExportKindGroups    ExportKind = "groups"    // One row per group with counts and metrics
+	ExportKindResponses ExportKind = "responses" // One row per response, flattened
+)
+
+// ExportRequest exports the result of a dashboard query
+type ExportRequest struct {
+	Query  DashboardQuery `json:"query"`
+	Format ExportFormat   `json:"format"`
+	Kind   ExportKind     `json:"kind"`
+	Async  bool           `json:"async,omitempty"` // Run as a job and download the file later
+}
+
+// ExportJobStatus is the state of an asynchronous export
+type ExportJobStatus string
+
+const (
+	ExportJobRunning   ExportJobStatus = "RUNNING"
+	ExportJobSucceeded ExportJobStatus = "SUCCEEDED"
+	ExportJobFailed    ExportJobStatus = "FAILED"
+)
+
+// ExportJob is an asynchronous export; the file is downloaded once it succeeded
+type ExportJob struct {
+	JobID       string          `json:"job_id" db:"job_id"`
+	Status      ExportJobStatus `json:"status" db:"status"`
+	Format      ExportFormat    `json:"format" db:"format"`
+	Kind        ExportKind      `json:"kind" db:"kind"`
+	Request     ExportRequest   `json:"request" db:"request"`       // JSONB
+	Identified  bool            `json:"identified" db:"identified"` // Holds identifiers; downloads need the permission to see them
+	Rows        int             `json:"rows" db:"row_count"`
+	Error       string          `json:"error,omitempty" db:"error"`
+	TenantID    string          `json:"tenant_id" db:"tenant_id"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt   time.Time       `json:"expires_at" db:"expires_at"`
 }
 
 // ProvenanceInfo tracks data sources in hybrid mode
@@ -123,6 +688,41 @@ type ProvenanceInfo struct {
 	HistoricalUnits []string `json:"historical_units"`
 }
 
+// UpdateResponseRequest represents API request to amend a response
+type UpdateResponseRequest struct {
+	EmployeeID string                 `json:"employee_id"`
+	Answers    map[string]interface{} `json:"answers"`
+}
+
+// WithdrawResponseRequest represents API request to withdraw or void a response
+type WithdrawResponseRequest struct {
+	EmployeeID string `json:"employee_id"` // Respondent withdrawing; empty when an admin voids
+	ActorID    string `json:"actor_id"`    // Admin voiding the response
+	Reason     string `json:"reason"`
+}
+
+// ErasureRequest represents API request to erase an employee's personal data
+type ErasureRequest struct {
+	RequestedBy string `json:"requested_by"`
+	Reason      string `json:"reason"`
+}
+
+// ErasureReceipt is the auditable record of a completed right-to-erasure request.
+// It never stores the employee ID, only a digest auditors can recompute.
+type ErasureReceipt struct {
+	ReceiptID           string     `json:"receipt_id" db:"receipt_id"`
+	TenantID            string     `json:"tenant_id" db:"tenant_id"`
+	SubjectDigest       string     `json:"subject_digest" db:"subject_digest"`
+	RequestedBy         string     `json:"requested_by" db:"requested_by"`
+	Reason              string     `json:"reason" db:"reason"`
+	EmployeesDeleted    int        `json:"employees_deleted" db:"employees_deleted"`
+	HistoryRowsDeleted  int        `json:"history_rows_deleted" db:"history_rows_deleted"`
+	ResponsesAnonymised int        `json:"responses_anonymised" db:"responses_anonymised"`
+	ExportsDeleted      int        `json:"exports_deleted" db:"exports_deleted"`
+	ViewsRefreshedAt    *time.Time `json:"views_refreshed_at" db:"views_refreshed_at"` // NULL = refresh pending
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+}
+
 // SubmitResponseRequest represents API request to submit response
 type SubmitResponseRequest struct {
 	EmployeeID string                 `json:"employee_id"`
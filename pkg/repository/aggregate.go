@@ -0,0 +1,447 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/lib/pq"
+)
+
+// Aggregate counts responses per distinct combination of the query's group-by fields
+// and computes the raw aggregates of each requested metric. Without group-by fields
+// a single group covers the whole filtered set.
+func (r *PostgresResponseRepository) Aggregate(ctx context.Context, q models.DashboardQuery) ([]models.GroupResult, error) {
+	query, args, err := buildAggregateQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	db, done, err := r.reader(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate responses: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []models.GroupResult
+	for rows.Next() {
+		group, err := scanGroupRow(rows, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group row: %w", err)
+		}
+		groups = append(groups, *group)
+	}
+
+	return groups, rows.Err()
+}
+
+// buildAggregateQuery renders the aggregation as an inner projection of group keys (g0..)
+// and numeric answers (m0..) over the filtered responses, grouped by the outer query.
+// Keys and question IDs are bound as parameters, never interpolated.
+func buildAggregateQuery(q models.DashboardQuery) (string, []interface{}, error) {
+	where, args := buildWhere(q)
+	bind := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	from := responseSource(q)
+	if q.UnitMapping != nil {
+		hist, cur := make([]string, 0, len(q.UnitMapping)), make([]string, 0, len(q.UnitMapping))
+		for h, c := range q.UnitMapping {
+			hist = append(hist, h)
+			cur = append(cur, c)
+		}
+		from += fmt.Sprintf(`
+			LEFT JOIN unnest(%s::text[], %s::text[]) AS um(hist, cur)
+			       ON um.hist = snapshot_core->>'unit_id'`, bind(pq.Array(hist)), bind(pq.Array(cur)))
+	}
+
+	var keys, groupCols []string
+	for i, field := range q.GroupBy {
+		keys = append(keys, fmt.Sprintf("%s AS g%d", groupExpr(q, field, bind), i))
+		groupCols = append(groupCols, fmt.Sprintf("g%d", i))
+	}
+
+	var answers, aggs []string
+	for i, m := range q.Metrics {
+		question := bind(m.QuestionID)
+		// CASE guarantees the cast only runs on JSON numbers
+		answers = append(answers, fmt.Sprintf(
+			"CASE WHEN jsonb_typeof(answers->%s) = 'number' THEN (answers->>%s)::numeric END AS m%d",
+			question, question, i))
+
+		top, bottom := m.Thresholds()
+		aggs = append(aggs,
+			fmt.Sprintf("COUNT(m%d) AS m%d_n", i, i),
+			fmt.Sprintf("AVG(m%d) AS m%d_avg", i, i),
+			fmt.Sprintf("STDDEV_SAMP(m%d) AS m%d_sd", i, i),
+			fmt.Sprintf("COUNT(*) FILTER (WHERE m%d >= %s) AS m%d_top", i, bind(top), i),
+			fmt.Sprintf("COUNT(*) FILTER (WHERE m%d <= %s) AS m%d_bottom", i, bind(bottom), i),
+		)
+	}
+	aggs = append([]string{"COUNT(*) AS n"}, aggs...)
+
+	if len(q.Benchmarks) > 0 {
+		return buildBenchmarkQuery(q, from, where, keys, groupCols, answers, aggs, args)
+	}
+
+	inner := append(keys, answers...)
+	if len(inner) == 0 {
+		inner = append(inner, "1")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM (
+			SELECT %s
+			FROM %s
+			%s
+		) t
+	`, strings.Join(append(groupCols, aggs...), ", "), strings.Join(inner, ", "), from, where)
+
+	if len(groupCols) > 0 {
+		cols := strings.Join(groupCols, ", ")
+		query += fmt.Sprintf(" GROUP BY %s ORDER BY %s", cols, cols)
+	}
+
+	return query, args, nil
+}
+
+// buildBenchmarkQuery extends the aggregation with reference populations in the same
+// statement. The filtered responses (base) are grouped as usual; the benchmark
+// population (bench) drops org filters and is aggregated once for the company and once
+// per distinct parent unit, whose subtree is resolved through the current ltree paths.
+func buildBenchmarkQuery(q models.DashboardQuery, from, where string, keys, groupCols, answers, aggs []string, args []interface{}) (string, []interface{}, error) {
+	bind := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	benchQuery := q
+	benchQuery.Filters = make(map[string]interface{}, len(q.Filters))
+	for field, value := range q.Filters {
+		if field != "department" && field != "unit_id" {
+			benchQuery.Filters[field] = value
+		}
+	}
+	benchWhere, args := appendWhere(benchQuery, args)
+
+	unitExpr := "snapshot_core->>'unit_id'"
+	if q.UnitMapping != nil {
+		unitExpr = "COALESCE(um.cur, snapshot_core->>'unit_id')"
+	}
+
+	baseCols := append(append([]string{}, keys...), answers...)
+	if len(baseCols) == 0 {
+		baseCols = append(baseCols, "1")
+	}
+	benchCols := append([]string{unitExpr + " AS unit"}, answers...)
+
+	grouped := fmt.Sprintf("SELECT %s FROM base", strings.Join(append(append([]string{}, groupCols...), aggs...), ", "))
+	if len(groupCols) > 0 {
+		grouped += " GROUP BY " + strings.Join(groupCols, ", ")
+	}
+
+	ctes := []string{
+		fmt.Sprintf("base AS (SELECT %s FROM %s %s)", strings.Join(baseCols, ", "), from, where),
+		fmt.Sprintf("bench AS (SELECT %s FROM %s %s)", strings.Join(benchCols, ", "), from, benchWhere),
+		fmt.Sprintf("grouped AS (%s)", grouped),
+	}
+
+	selectCols := make([]string, 0, len(groupCols)+len(aggs)*(1+len(q.Benchmarks)))
+	for _, col := range groupCols {
+		selectCols = append(selectCols, "g."+col)
+	}
+	selectCols = append(selectCols, prefixAggs("g", aggs)...)
+
+	var joins []string
+	for _, scope := range q.Benchmarks {
+		switch scope {
+		case models.BenchmarkCompany:
+			ctes = append(ctes, fmt.Sprintf("company AS (SELECT %s FROM bench)", strings.Join(aggs, ", ")))
+			joins = append(joins, "CROSS JOIN company c")
+			selectCols = append(selectCols, "NULL")
+			selectCols = append(selectCols, prefixAggs("c", aggs)...)
+
+		case models.BenchmarkParentUnit:
+			orgCol := orgGroupColumn(q)
+			if orgCol == "" {
+				return "", nil, fmt.Errorf("parent_unit benchmark requires grouping by department or unit_id")
+			}
+			tenant := bind(q.TenantID)
+			ctes = append(ctes,
+				fmt.Sprintf(`parents AS (
+					SELECT DISTINCT p.unit_id, p.path
+					FROM grouped g
+					JOIN org_units_history u ON u.tenant_id = %[1]s AND u.valid_to IS NULL AND u.unit_id = g.%[2]s
+					JOIN org_units_history p ON p.tenant_id = %[1]s AND p.valid_to IS NULL AND p.unit_id = u.parent_unit_id
+				)`, tenant, orgCol),
+				fmt.Sprintf(`parent_aggs AS (
+					SELECT p.unit_id AS parent_id, %[2]s
+					FROM parents p
+					JOIN org_units_history bu ON bu.tenant_id = %[1]s AND bu.valid_to IS NULL AND bu.path <@ p.path
+					JOIN bench ON bench.unit = bu.unit_id
+					GROUP BY p.unit_id
+				)`, tenant, strings.Join(aggs, ", ")),
+			)
+			joins = append(joins, fmt.Sprintf(`
+				LEFT JOIN org_units_history gu ON gu.tenant_id = %s AND gu.valid_to IS NULL AND gu.unit_id = g.%s
+				LEFT JOIN parent_aggs pa ON pa.parent_id = gu.parent_unit_id`, tenant, orgCol))
+			selectCols = append(selectCols, "gu.parent_unit_id")
+			selectCols = append(selectCols, prefixAggs("pa", aggs)...)
+
+		default:
+			return "", nil, fmt.Errorf("unknown benchmark scope %q", scope)
+		}
+	}
+
+	query := fmt.Sprintf(`
+		WITH %s
+		SELECT %s
+		FROM grouped g
+		%s
+	`, strings.Join(ctes, ",\n"), strings.Join(selectCols, ", "), strings.Join(joins, "\n"))
+
+	if len(groupCols) > 0 {
+		query += " ORDER BY " + strings.Join(prefixCols("g", groupCols), ", ")
+	}
+
+	return query, args, nil
+}
+
+// prefixAggs selects the aliased aggregates of a CTE, counting missing rows as zero
+func prefixAggs(alias string, aggs []string) []string {
+	cols := make([]string, len(aggs))
+	for i, agg := range aggs {
+		name := agg[strings.LastIndex(agg, " AS ")+4:]
+		if strings.HasSuffix(name, "_avg") || strings.HasSuffix(name, "_sd") {
+			cols[i] = alias + "." + name
+		} else {
+			cols[i] = fmt.Sprintf("COALESCE(%s.%s, 0)", alias, name)
+		}
+	}
+	return cols
+}
+
+func prefixCols(alias string, cols []string) []string {
+	out := make([]string, len(cols))
+	for i, col := range cols {
+		out[i] = alias + "." + col
+	}
+	return out
+}
+
+// orgGroupColumn returns the group column holding the unit ID, if any
+func orgGroupColumn(q models.DashboardQuery) string {
+	for i, field := range q.GroupBy {
+		if field == "unit_id" || (field == "department" && q.UnitMapping != nil) {
+			return fmt.Sprintf("g%d", i)
+		}
+	}
+	return ""
+}
+
+// groupExpr returns the SQL expression for one group-by dimension
+func groupExpr(q models.DashboardQuery, field string, bind func(interface{}) string) string {
+	switch {
+	case field == models.GroupBySurvey:
+		return "survey_id"
+	case field == models.GroupByTimeBucket:
+		// submitted_at is stored in UTC; buckets follow the tenant's local calendar
+		tz := q.TimeZone
+		if tz == "" {
+			tz = "UTC"
+		}
+		return fmt.Sprintf("to_char(date_trunc(%s, (submitted_at AT TIME ZONE 'UTC') AT TIME ZONE %s), 'YYYY-MM-DD')",
+			bind(string(q.TimeBucket)), bind(tz))
+	case strings.HasPrefix(field, models.AnswerFieldPrefix):
+		// Crosstabs group on the answer's text form; multi-choice answers group as JSON arrays
+		return "answers->>" + bind(strings.TrimPrefix(field, models.AnswerFieldPrefix))
+	case q.UnitMapping != nil && (field == "department" || field == "unit_id"):
+		// Grouped by current unit ID; the service relabels departments with current names
+		return "COALESCE(um.cur, snapshot_core->>'unit_id')"
+	default:
+		return "snapshot_core->>" + bind(field)
+	}
+}
+
+// SnapshotKeys returns the snapshot_core keys present across the filtered responses, sorted
+func (r *PostgresResponseRepository) SnapshotKeys(ctx context.Context, q models.DashboardQuery) ([]string, error) {
+	where, args := buildWhere(q)
+	from := responseSource(q)
+
+	db, done, err := r.reader(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT DISTINCT jsonb_object_keys(snapshot_core) AS key
+		FROM %s
+		%s
+		ORDER BY key
+	`, from, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// SnapshotSchemaRepository reports the snapshot_core keys a tenant's responses carry
+type SnapshotSchemaRepository interface {
+	SnapshotSchema(ctx context.Context, tenantID string) (models.SnapshotSchema, error)
+}
+
+// SnapshotSchema returns every snapshot_core key in the tenant's responses with the JSON
+// types of its values. It reads all of the tenant's responses, so callers should cache it.
+func (r *PostgresResponseRepository) SnapshotSchema(ctx context.Context, tenantID string) (models.SnapshotSchema, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT e.key, jsonb_typeof(e.value)
+		FROM survey_responses, jsonb_each(snapshot_core) AS e
+		WHERE tenant_id = $1
+		ORDER BY 1, 2
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot schema: %w", err)
+	}
+	defer rows.Close()
+
+	schema := make(models.SnapshotSchema)
+	for rows.Next() {
+		var key, typ string
+		if err := rows.Scan(&key, &typ); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot schema: %w", err)
+		}
+		schema[key] = append(schema[key], typ)
+	}
+	return schema, rows.Err()
+}
+
+// DistinctValues returns the distinct values of a snapshot_core key across the filtered responses
+func (r *PostgresResponseRepository) DistinctValues(ctx context.Context, q models.DashboardQuery, field string) ([]string, error) {
+	where, args := buildWhere(q)
+	from := responseSource(q)
+	args = append(args, field)
+	query := fmt.Sprintf(`
+		SELECT DISTINCT snapshot_core->>$%d
+		FROM %s
+		%s
+	`, len(args), from, where)
+
+	db, done, err := r.reader(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct values: %w", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v sql.NullString
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("failed to scan distinct value: %w", err)
+		}
+		if v.Valid {
+			values = append(values, v.String)
+		}
+	}
+
+	return values, rows.Err()
+}
+
+func scanGroupRow(row rowScanner, q models.DashboardQuery) (*models.GroupResult, error) {
+	var group models.GroupResult
+
+	keys := make([]sql.NullString, len(q.GroupBy))
+	dest := make([]interface{}, 0, len(q.GroupBy)+(1+len(q.Benchmarks))*(2+5*len(q.Metrics)))
+	for i := range keys {
+		dest = append(dest, &keys[i])
+	}
+
+	var fill []func()
+	dest, f := metricDest(dest, &group.Count, &group.Metrics, q.Metrics)
+	fill = append(fill, f)
+
+	group.Benchmarks = make([]models.Benchmark, len(q.Benchmarks))
+	parents := make([]sql.NullString, len(q.Benchmarks))
+	for i, scope := range q.Benchmarks {
+		b := &group.Benchmarks[i]
+		b.Scope = scope
+		dest = append(dest, &parents[i])
+		dest, f = metricDest(dest, &b.Count, &b.Metrics, q.Metrics)
+		fill = append(fill, f)
+	}
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	group.Key = make(map[string]string, len(q.GroupBy))
+	for i, field := range q.GroupBy {
+		group.Key[field] = keys[i].String // NULL → ""
+	}
+	for _, f := range fill {
+		f()
+	}
+	for i := range group.Benchmarks {
+		group.Benchmarks[i].UnitID = parents[i].String
+	}
+	if len(q.Benchmarks) == 0 {
+		group.Benchmarks = nil
+	}
+
+	return &group, nil
+}
+
+// metricDest appends scan destinations for a count and the raw aggregates of each metric.
+// The returned func copies the nullable aggregates into the metrics after scanning.
+func metricDest(dest []interface{}, count *int, metrics *[]models.MetricValue, specs []models.MetricSpec) ([]interface{}, func()) {
+	dest = append(dest, count)
+	if len(specs) == 0 {
+		return dest, func() {}
+	}
+
+	*metrics = make([]models.MetricValue, len(specs))
+	means := make([]sql.NullFloat64, len(specs))
+	stddevs := make([]sql.NullFloat64, len(specs))
+	for i := range specs {
+		m := &(*metrics)[i]
+		dest = append(dest, &m.Respondents, &means[i], &stddevs[i], &m.Top, &m.Bottom)
+	}
+
+	return dest, func() {
+		for i, spec := range specs {
+			m := &(*metrics)[i]
+			m.Type = spec.Type
+			m.QuestionID = spec.QuestionID
+			m.Mean = means[i].Float64
+			m.StdDev = stddevs[i].Float64
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ArchiveStore keeps responses of archived months in cold storage, one file per
+// tenant per month
+type ArchiveStore interface {
+	Write(ctx context.Context, tenantID string, month time.Time, responses []models.Response) error
+	Read(ctx context.Context, tenantID string, month time.Time) ([]models.Response, error)
+	Scan(ctx context.Context, tenantID string, month time.Time, filter ArchiveFilter, limit int) ([]models.Response, error)
+	Months(ctx context.Context, tenantID string) ([]time.Time, error)
+}
+
+// ArchiveFilter selects archived rows while a file is scanned, so rows a query cannot
+// match are never decoded or held in memory
+type ArchiveFilter struct {
+	From       time.Time
+	To         time.Time
+	SurveyID   string // Empty for every survey
+	ActiveOnly bool
+}
+
+// matches applies the filter to a row before it is decoded
+func (f ArchiveFilter) matches(row *archivedResponse) bool {
+	if row.SubmittedAt.Before(f.From) || row.SubmittedAt.After(f.To) {
+		return false
+	}
+	if f.SurveyID != "" && row.SurveyID != f.SurveyID {
+		return false
+	}
+	return !f.ActiveOnly || row.Status == string(models.ResponseStatusActive)
+}
+
+// archivedResponse is the Parquet row layout. Snapshot and answers are kept as JSON
+// so archives survive changes to snapshot attributes and question sets. The archive
+// service anonymises rows before writing them, so employee and version IDs hold
+// tombstones.
+type archivedResponse struct {
+	ResponseID   string     `parquet:"response_id"`
+	TenantID     string     `parquet:"tenant_id,dict"`
+	SurveyID     string     `parquet:"survey_id,dict"`
+	EmployeeID   string     `parquet:"employee_id"`
+	SubmittedAt  time.Time  `parquet:"submitted_at,timestamp(microsecond)"`
+	CreatedAt    time.Time  `parquet:"created_at,timestamp(microsecond)"`
+	UpdatedAt    *time.Time `parquet:"updated_at,optional"`
+	Status       string     `parquet:"status,dict"`
+	Revision     int32      `parquet:"revision"`
+	VersionID    string     `parquet:"version_id"`
+	SnapshotCore []byte     `parquet:"snapshot_core,json"`
+	Answers      []byte     `parquet:"answers,json"`
+}
+
+// LocalArchiveStore writes Parquet files under root as <tenant>/<YYYY-MM>.parquet
+type LocalArchiveStore struct {
+	root string
+}
+
+func NewLocalArchiveStore(root string) *LocalArchiveStore {
+	return &LocalArchiveStore{root: root}
+}
+
+// Write replaces the month's file for the tenant. The file is written under a
+// temporary name and renamed, so readers never see a partial archive.
+func (s *LocalArchiveStore) Write(ctx context.Context, tenantID string, month time.Time, responses []models.Response) error {
+	path, err := s.path(tenantID, month)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	rows := make([]archivedResponse, len(responses))
+	for i, r := range responses {
+		snapshot, err := json.Marshal(r.SnapshotCore)
+		if err != nil {
+			return fmt.Errorf("failed to encode snapshot of %s: %w", r.ResponseID, err)
+		}
+		rows[i] = archivedResponse{
+			ResponseID:   r.ResponseID,
+			TenantID:     r.TenantID,
+			SurveyID:     r.SurveyID,
+			EmployeeID:   r.EmployeeID,
+			SubmittedAt:  r.SubmittedAt.UTC(),
+			CreatedAt:    r.CreatedAt.UTC(),
+			Status:       string(r.Status),
+			UpdatedAt:    r.UpdatedAt,
+			Revision:     int32(r.Revision),
+			VersionID:    r.VersionID,
+			SnapshotCore: snapshot,
+			Answers:      r.Answers,
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := parquet.WriteFile(tmp, rows, parquet.Compression(&parquet.Zstd)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write archive %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to publish archive %s: %w", path, err)
+	}
+	return nil
+}
+
+// Read returns the archived responses of a tenant's month; none if it was never archived
+func (s *LocalArchiveStore) Read(ctx context.Context, tenantID string, month time.Time) ([]models.Response, error) {
+	path, err := s.path(tenantID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parquet.ReadFile[archivedResponse](path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", path, err)
+	}
+
+	responses := make([]models.Response, len(rows))
+	for i := range rows {
+		if err := rows[i].decode(&responses[i]); err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
+
+// archiveScanBatch is how many rows Scan reads from the file at a time
+const archiveScanBatch = 512
+
+// Scan streams a tenant's month and returns the rows matching filter, stopping as soon
+// as more than limit match, so at most limit+1 rows are returned. A month that was
+// never archived has no rows.
+func (s *LocalArchiveStore) Scan(ctx context.Context, tenantID string, month time.Time, filter ArchiveFilter, limit int) ([]models.Response, error) {
+	path, err := s.path(tenantID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := parquet.NewGenericReader[archivedResponse](f)
+	defer reader.Close()
+
+	var responses []models.Response
+	batch := make([]archivedResponse, archiveScanBatch)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, readErr := reader.Read(batch)
+		for i := 0; i < n; i++ {
+			if !filter.matches(&batch[i]) {
+				continue
+			}
+			var r models.Response
+			if err := batch[i].decode(&r); err != nil {
+				return nil, err
+			}
+			responses = append(responses, r)
+			if len(responses) > limit {
+				return responses, nil
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			return responses, nil
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", path, readErr)
+		}
+	}
+}
+
+// decode converts a Parquet row back to a response
+func (row *archivedResponse) decode(r *models.Response) error {
+	*r = models.Response{
+		ResponseID:  row.ResponseID,
+		TenantID:    row.TenantID,
+		SurveyID:    row.SurveyID,
+		EmployeeID:  row.EmployeeID,
+		SubmittedAt: row.SubmittedAt,
+		CreatedAt:   row.CreatedAt,
+		Status:      models.ResponseStatus(row.Status),
+		UpdatedAt:   row.UpdatedAt,
+		Revision:    int(row.Revision),
+		VersionID:   row.VersionID,
+		Answers:     append(json.RawMessage(nil), row.Answers...), // The reader reuses row buffers
+	}
+	if err := json.Unmarshal(row.SnapshotCore, &r.SnapshotCore); err != nil {
+		return fmt.Errorf("failed to decode snapshot of %s: %w", row.ResponseID, err)
+	}
+	return nil
+}
+
+// Months lists the archived months of a tenant, oldest first
+func (s *LocalArchiveStore) Months(ctx context.Context, tenantID string) ([]time.Time, error) {
+	dir, err := s.tenantDir(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+
+	var months []time.Time
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".parquet")
+		if !ok || e.IsDir() {
+			continue
+		}
+		month, err := time.Parse("2006-01", name)
+		if err != nil {
+			continue // Not an archive file
+		}
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	return months, nil
+}
+
+func (s *LocalArchiveStore) path(tenantID string, month time.Time) (string, error) {
+	dir, err := s.tenantDir(tenantID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, month.UTC().Format("2006-01")+".parquet"), nil
+}
+
+// tenantDir rejects tenant IDs that would escape the archive root
+func (s *LocalArchiveStore) tenantDir(tenantID string) (string, error) {
+	if tenantID == "" || tenantID != filepath.Base(tenantID) || tenantID == "." || tenantID == ".." {
+		return "", fmt.Errorf("invalid tenant ID for archive: %q", tenantID)
+	}
+	return filepath.Join(s.root, tenantID), nil
+}
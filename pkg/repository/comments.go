@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// Comments returns how many filtered responses answered questionID with non-empty text,
+// and a random sample of up to limit of those answers matching search. Only the text is
+// selected, so nothing links a comment back to its response or snapshot.
+func (r *PostgresResponseRepository) Comments(ctx context.Context, q models.DashboardQuery, questionID, search string, limit int) (int, []string, error) {
+	where, args := buildWhere(q)
+	from := responseSource(q)
+	bind := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	question := bind(questionID)
+	answered := fmt.Sprintf(`%[1]s
+			  AND jsonb_typeof(answers->%[2]s) = 'string'
+			  AND btrim(answers->>%[2]s) <> ''`, where, question)
+
+	// The search predicates sit in the scan of survey_responses itself, so the GIN
+	// index over all string answers serves the first; the second restricts the match
+	// to this question
+	match := answered
+	if search != "" {
+		tsquery := fmt.Sprintf("websearch_to_tsquery('english', %s)", bind(search))
+		match += fmt.Sprintf(`
+			  AND jsonb_to_tsvector('english', answers, '["string"]') @@ %[1]s
+			  AND to_tsvector('english', answers->>%[2]s) @@ %[1]s`, tsquery, question)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT c.n, s.comment
+		FROM (SELECT COUNT(*) AS n FROM %[1]s %[2]s) c
+		LEFT JOIN LATERAL (
+			SELECT answers->>%[3]s AS comment
+			FROM %[1]s
+			%[4]s
+			ORDER BY random()
+			LIMIT %[5]s
+		) s ON true
+	`, from, answered, question, match, bind(limit))
+
+	db, done, err := r.reader(ctx, q)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer done()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	var total int
+	comments := []string{}
+	for rows.Next() {
+		var comment sql.NullString
+		if err := rows.Scan(&total, &comment); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		if comment.Valid {
+			comments = append(comments, comment.String)
+		}
+	}
+
+	return total, comments, rows.Err()
+}
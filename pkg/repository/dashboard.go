@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// DashboardRepository handles saved dashboard definitions
+type DashboardRepository interface {
+	Create(ctx context.Context, dashboard *models.Dashboard) error
+	Update(ctx context.Context, dashboard *models.Dashboard) error
+	Delete(ctx context.Context, tenantID, dashboardID string) error
+	GetByID(ctx context.Context, tenantID, dashboardID string) (*models.Dashboard, error)
+	List(ctx context.Context, tenantID string) ([]models.Dashboard, error)
+}
+
+// PostgresDashboardRepository implements DashboardRepository
+type PostgresDashboardRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDashboardRepository(db *sql.DB) *PostgresDashboardRepository {
+	return &PostgresDashboardRepository{db: db}
+}
+
+const dashboardColumns = `
+		dashboard_id, title, widgets, tenant_id, created_at, updated_at`
+
+func (r *PostgresDashboardRepository) Create(ctx context.Context, dashboard *models.Dashboard) error {
+	widgetsJSON, err := json.Marshal(dashboard.Widgets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal widgets: %w", err)
+	}
+
+	if dashboard.DashboardID == "" {
+		dashboard.DashboardID = GenerateID()
+	}
+
+	query := `
+		INSERT INTO dashboards (dashboard_id, title, widgets, tenant_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		dashboard.DashboardID,
+		dashboard.Title,
+		widgetsJSON,
+		dashboard.TenantID,
+	).Scan(&dashboard.CreatedAt, &dashboard.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create dashboard: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDashboardRepository) Update(ctx context.Context, dashboard *models.Dashboard) error {
+	widgetsJSON, err := json.Marshal(dashboard.Widgets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal widgets: %w", err)
+	}
+
+	query := `
+		UPDATE dashboards
+		SET title = $3,
+		    widgets = $4,
+		    updated_at = NOW()
+		WHERE dashboard_id = $1
+		  AND tenant_id = $2
+		RETURNING created_at, updated_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		dashboard.DashboardID,
+		dashboard.TenantID,
+		dashboard.Title,
+		widgetsJSON,
+	).Scan(&dashboard.CreatedAt, &dashboard.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("dashboard %w: %s", ErrNotFound, dashboard.DashboardID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update dashboard: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDashboardRepository) Delete(ctx context.Context, tenantID, dashboardID string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM dashboards WHERE dashboard_id = $1 AND tenant_id = $2
+	`, dashboardID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dashboard: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("dashboard %w: %s", ErrNotFound, dashboardID)
+	}
+	return nil
+}
+
+// GetByID returns the dashboard, or nil if it does not exist
+func (r *PostgresDashboardRepository) GetByID(ctx context.Context, tenantID, dashboardID string) (*models.Dashboard, error) {
+	query := `SELECT ` + dashboardColumns + `
+		FROM dashboards
+		WHERE dashboard_id = $1
+		  AND tenant_id = $2
+	`
+
+	dashboard, err := scanDashboardRow(r.db.QueryRowContext(ctx, query, dashboardID, tenantID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dashboard: %w", err)
+	}
+
+	return dashboard, nil
+}
+
+func (r *PostgresDashboardRepository) List(ctx context.Context, tenantID string) ([]models.Dashboard, error) {
+	query := `SELECT ` + dashboardColumns + `
+		FROM dashboards
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboards: %w", err)
+	}
+	defer rows.Close()
+
+	var dashboards []models.Dashboard
+	for rows.Next() {
+		dashboard, err := scanDashboardRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard: %w", err)
+		}
+		dashboards = append(dashboards, *dashboard)
+	}
+
+	return dashboards, rows.Err()
+}
+
+func scanDashboardRow(row rowScanner) (*models.Dashboard, error) {
+	var dashboard models.Dashboard
+	var widgetsJSON []byte
+
+	err := row.Scan(
+		&dashboard.DashboardID,
+		&dashboard.Title,
+		&widgetsJSON,
+		&dashboard.TenantID,
+		&dashboard.CreatedAt,
+		&dashboard.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(widgetsJSON, &dashboard.Widgets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal widgets: %w", err)
+	}
+
+	return &dashboard, nil
+}
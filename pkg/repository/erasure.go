@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/lib/pq"
+)
+
+// ErasureRepository handles right-to-erasure operations
+type ErasureRepository interface {
+	EraseEmployee(ctx context.Context, employeeID string, receipt *models.ErasureReceipt) error
+	MarkViewsRefreshed(ctx context.Context, receiptID string) error
+	GetReceipt(ctx context.Context, tenantID, receiptID string) (*models.ErasureReceipt, error)
+	RefreshViews(ctx context.Context) error
+}
+
+// PostgresErasureRepository implements ErasureRepository
+type PostgresErasureRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresErasureRepository(db *sql.DB) *PostgresErasureRepository {
+	return &PostgresErasureRepository{db: db}
+}
+
+// EraseEmployee deletes the employee's live and historical records, anonymises their
+// responses in place and stores the receipt, all in one transaction. Response rows are
+// kept for aggregates but lose every identifying or quasi-identifying snapshot key.
+// Detached partitions are anonymised too, as Attach would bring their rows back.
+// Archived months need no changes: their rows were anonymised the same way on export.
+// Identified exports are deleted when their survey and time range cover one of the
+// employee's responses.
+func (r *PostgresErasureRepository) EraseEmployee(ctx context.Context, employeeID string, receipt *models.ErasureReceipt) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin erasure: %w", err)
+	}
+	defer tx.Rollback()
+
+	employees, err := execCount(ctx, tx, `
+		DELETE FROM employees WHERE employee_id = $1 AND tenant_id = $2
+	`, employeeID, receipt.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete employee: %w", err)
+	}
+
+	history, err := execCount(ctx, tx, `
+		DELETE FROM employee_history WHERE employee_id = $1 AND tenant_id = $2
+	`, employeeID, receipt.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete employee history: %w", err)
+	}
+
+	partitions, err := listPartitions(ctx, tx)
+	if err != nil {
+		return err
+	}
+	tables := []string{"survey_responses"}
+	for _, p := range partitions {
+		if !p.Attached {
+			tables = append(tables, pq.QuoteIdentifier(p.Name))
+		}
+	}
+
+	// The tombstone is derived from the receipt, not the employee, so it cannot be reversed
+	tombstone := "erased_" + receipt.ReceiptID
+	exports, responses := 0, 0
+	for _, table := range tables {
+		n, err := eraseFrom(ctx, tx, table, employeeID, receipt.TenantID, tombstone)
+		if err != nil {
+			return err
+		}
+		exports += n.exports
+		responses += n.responses
+	}
+
+	// Attached rows renamed their claims through the trigger; detached rows have none
+	_, err = tx.ExecContext(ctx, `
+		UPDATE response_employee_claims SET employee_id = $3
+		WHERE employee_id = $1 AND tenant_id = $2
+	`, employeeID, receipt.TenantID, tombstone)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise response claims: %w", err)
+	}
+
+	// Keep the invitation so participation rates stay stable, but not who it was
+	_, err = tx.ExecContext(ctx, `
+		UPDATE survey_eligibility SET employee_id = $3
+		WHERE employee_id = $1 AND tenant_id = $2
+	`, employeeID, receipt.TenantID, tombstone)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise survey eligibility: %w", err)
+	}
+
+	if employees == 0 && history == 0 && responses == 0 {
+		return fmt.Errorf("employee %w: %s", ErrNotFound, employeeID)
+	}
+
+	receipt.EmployeesDeleted = employees
+	receipt.HistoryRowsDeleted = history
+	receipt.ResponsesAnonymised = responses
+	receipt.ExportsDeleted = exports
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO erasure_receipts (
+			receipt_id, tenant_id, subject_digest, requested_by, reason,
+			employees_deleted, history_rows_deleted, responses_anonymised, exports_deleted
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at
+	`,
+		receipt.ReceiptID,
+		receipt.TenantID,
+		receipt.SubjectDigest,
+		receipt.RequestedBy,
+		receipt.Reason,
+		receipt.EmployeesDeleted,
+		receipt.HistoryRowsDeleted,
+		receipt.ResponsesAnonymised,
+		receipt.ExportsDeleted,
+	).Scan(&receipt.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store erasure receipt: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit erasure: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresErasureRepository) MarkViewsRefreshed(ctx context.Context, receiptID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE erasure_receipts SET views_refreshed_at = NOW() WHERE receipt_id = $1
+	`, receiptID)
+	if err != nil {
+		return fmt.Errorf("failed to update erasure receipt: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresErasureRepository) GetReceipt(ctx context.Context, tenantID, receiptID string) (*models.ErasureReceipt, error) {
+	query := `
+		SELECT receipt_id, tenant_id, subject_digest, requested_by, reason,
+		       employees_deleted, history_rows_deleted, responses_anonymised,
+		       exports_deleted, views_refreshed_at, created_at
+		FROM erasure_receipts
+		WHERE receipt_id = $1
+		  AND tenant_id = $2
+	`
+
+	var receipt models.ErasureReceipt
+	err := r.db.QueryRowContext(ctx, query, receiptID, tenantID).Scan(
+		&receipt.ReceiptID,
+		&receipt.TenantID,
+		&receipt.SubjectDigest,
+		&receipt.RequestedBy,
+		&receipt.Reason,
+		&receipt.EmployeesDeleted,
+		&receipt.HistoryRowsDeleted,
+		&receipt.ResponsesAnonymised,
+		&receipt.ExportsDeleted,
+		&receipt.ViewsRefreshedAt,
+		&receipt.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("erasure receipt %w: %s", ErrNotFound, receiptID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get erasure receipt: %w", err)
+	}
+
+	return &receipt, nil
+}
+
+// RefreshViews recomputes the dashboard materialized views
+func (r *PostgresErasureRepository) RefreshViews(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `SELECT refresh_dashboard_views()`); err != nil {
+		return fmt.Errorf("failed to refresh dashboard views: %w", err)
+	}
+	return nil
+}
+
+// erasedCounts is what eraseFrom deleted and anonymised in one table
+type erasedCounts struct {
+	exports   int
+	responses int
+}
+
+// eraseFrom erases the employee from table, survey_responses or a detached partition:
+// it deletes identified exports that can hold their responses, then anonymises the
+// responses and their archived revisions
+func eraseFrom(ctx context.Context, tx *sql.Tx, table, employeeID, tenantID, tombstone string) (erasedCounts, error) {
+	var n erasedCounts
+	var err error
+
+	// Response exports always name a survey; times are stored as UTC wall-clock values.
+	// Exports without identifiers hold no more than the anonymised responses do.
+	n.exports, err = execCount(ctx, tx, fmt.Sprintf(`
+		DELETE FROM export_jobs j
+		WHERE j.tenant_id = $1
+		  AND j.identified
+		  AND EXISTS (
+		      SELECT 1 FROM %s r
+		      WHERE r.tenant_id = j.tenant_id
+		        AND r.employee_id = $2
+		        AND r.survey_id = j.request->'query'->>'survey_id'
+		        AND r.submitted_at >= (j.request->'query'->'time_range'->>'from')::timestamptz AT TIME ZONE 'UTC'
+		        AND r.submitted_at <= (j.request->'query'->'time_range'->>'to')::timestamptz AT TIME ZONE 'UTC'
+		  )
+	`, table), tenantID, employeeID)
+	if err != nil {
+		return n, fmt.Errorf("failed to delete identified exports: %w", err)
+	}
+
+	n.responses, err = execCount(ctx, tx, fmt.Sprintf(`
+		UPDATE %s
+		SET employee_id = $3,
+		    version_id = $3,
+		    snapshot_core = snapshot_core
+		        - 'employee_name' - 'employee_email' - 'employee_pseudonym'
+		        - 'age' - 'tenure'
+		WHERE employee_id = $1 AND tenant_id = $2
+	`, table), employeeID, tenantID, tombstone)
+	if err != nil {
+		return n, fmt.Errorf("failed to anonymise responses: %w", err)
+	}
+
+	// Archived revisions carry the same snapshots and may name the employee as editor
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE survey_response_versions v
+		SET version_id = $2,
+		    changed_by = CASE WHEN v.changed_by = $3 THEN $2 ELSE v.changed_by END,
+		    snapshot_core = v.snapshot_core
+		        - 'employee_name' - 'employee_email' - 'employee_pseudonym'
+		        - 'age' - 'tenure'
+		FROM %s r
+		WHERE r.response_id = v.response_id
+		  AND r.employee_id = $2
+		  AND r.tenant_id = $1
+	`, table), tenantID, tombstone, employeeID)
+	if err != nil {
+		return n, fmt.Errorf("failed to anonymise response versions: %w", err)
+	}
+	return n, nil
+}
+
+func execCount(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
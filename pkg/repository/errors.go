@@ -0,0 +1,27 @@
+package repository
+
+import "errors"
+
+// Error kinds. Specific errors wrap or match a kind, so callers can handle a whole
+// class of failures with errors.Is instead of listing every sentinel.
+var (
+	// ErrNotFound is matched by errors for rows that do not exist or belong to another tenant
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is matched by errors for writes that clash with the current state
+	ErrConflict = errors.New("conflict")
+)
+
+// kindError is a sentinel with its own message that also matches its kind
+type kindError struct {
+	kind    error
+	message string
+}
+
+// NewKindError returns a sentinel error reading message that matches kind with errors.Is
+func NewKindError(kind error, message string) error {
+	return &kindError{kind: kind, message: message}
+}
+
+func (e *kindError) Error() string { return e.message }
+
+func (e *kindError) Unwrap() error { return e.kind }
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// maxExplainedArgLength is the longest argument shown in explanations. Longer ones may
+// hold free-text answer values, so they are omitted entirely.
+const maxExplainedArgLength = 200
+
+// Explain renders the statements a dashboard query runs on path and asks Postgres for
+// their plans. EXPLAIN without ANALYZE only plans, so nothing is executed; archived rows
+// are still staged so the plans cover them.
+func (r *PostgresResponseRepository) Explain(ctx context.Context, q models.DashboardQuery, path models.QueryPath) ([]models.ExplainedStatement, error) {
+	type statement struct {
+		purpose string
+		build   func(models.DashboardQuery) (string, []interface{}, error)
+	}
+
+	var statements []statement
+	if path == models.QueryPathMaterializedView {
+		statements = append(statements, statement{"summary", buildSummaryQuery})
+	} else {
+		statements = append(statements, statement{"responses", buildResponsesQuery})
+		if len(q.GroupBy) > 0 || len(q.Metrics) > 0 || len(q.Benchmarks) > 0 {
+			statements = append(statements, statement{"aggregate", buildAggregateQuery})
+		}
+	}
+
+	db, done, err := r.reader(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	explained := make([]models.ExplainedStatement, 0, len(statements))
+	for _, st := range statements {
+		query, args, err := st.build(q)
+		if err != nil {
+			return nil, err
+		}
+
+		var plan []byte
+		if err := db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&plan); err != nil {
+			return nil, fmt.Errorf("failed to explain %s query: %w", st.purpose, err)
+		}
+
+		// The plan is a one-element array whose root node carries the estimates
+		var root []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+				Cost float64 `json:"Total Cost"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal(plan, &root); err != nil || len(root) == 0 {
+			return nil, fmt.Errorf("failed to parse %s query plan: %v", st.purpose, err)
+		}
+
+		explained = append(explained, models.ExplainedStatement{
+			Purpose:       st.purpose,
+			SQL:           query,
+			Args:          explainedArgs(args),
+			Plan:          plan,
+			EstimatedRows: root[0].Plan.Rows,
+			EstimatedCost: root[0].Plan.Cost,
+		})
+	}
+	return explained, nil
+}
+
+// explainedArgs replaces long string arguments with their size
+func explainedArgs(args []interface{}) []interface{} {
+	shown := make([]interface{}, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok && len(s) > maxExplainedArgLength {
+			arg = fmt.Sprintf("(%d bytes omitted)", len(s))
+		}
+		shown[i] = arg
+	}
+	return shown
+}
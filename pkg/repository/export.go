@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// ExportJobRepository stores asynchronous exports and their finished files. Files are
+// stored as numbered chunks while they are written and expire with their job.
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *models.ExportJob, retention time.Duration) error
+	GetByID(ctx context.Context, tenantID, jobID string) (*models.ExportJob, error)
+	WriteChunk(ctx context.Context, tenantID, jobID string, seq int, data []byte) error
+	Content(ctx context.Context, tenantID, jobID string, w io.Writer) error
+	Complete(ctx context.Context, tenantID, jobID string, rows int, retention time.Duration) error
+	Fail(ctx context.Context, tenantID, jobID, reason string) error
+	DeleteExpired(ctx context.Context, tenantID string) (int, error)
+}
+
+// PostgresExportJobRepository implements ExportJobRepository
+type PostgresExportJobRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresExportJobRepository(db *sql.DB) *PostgresExportJobRepository {
+	return &PostgresExportJobRepository{db: db}
+}
+
+// Create records a new running job that expires after retention unless it completes
+func (r *PostgresExportJobRepository) Create(ctx context.Context, job *models.ExportJob, retention time.Duration) error {
+	requestJSON, err := json.Marshal(job.Request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export request: %w", err)
+	}
+
+	if job.JobID == "" {
+		job.JobID = GenerateID()
+	}
+	job.Status = models.ExportJobRunning
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO export_jobs (job_id, status, format, kind, request, identified, tenant_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW() + $8 * INTERVAL '1 second')
+		RETURNING created_at, expires_at
+	`, job.JobID, job.Status, job.Format, job.Kind, requestJSON, job.Identified, job.TenantID,
+		retention.Seconds()).Scan(&job.CreatedAt, &job.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create export job: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns the job without its file, or nil if it does not exist or expired
+func (r *PostgresExportJobRepository) GetByID(ctx context.Context, tenantID, jobID string) (*models.ExportJob, error) {
+	var job models.ExportJob
+	var requestJSON []byte
+	var rows sql.NullInt64
+	var reason sql.NullString
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT job_id, status, format, kind, request, identified, row_count, error,
+		       tenant_id, created_at, completed_at, expires_at
+		FROM export_jobs
+		WHERE job_id = $1
+		  AND tenant_id = $2
+		  AND expires_at > NOW()
+	`, jobID, tenantID).Scan(
+		&job.JobID,
+		&job.Status,
+		&job.Format,
+		&job.Kind,
+		&requestJSON,
+		&job.Identified,
+		&rows,
+		&reason,
+		&job.TenantID,
+		&job.CreatedAt,
+		&job.CompletedAt,
+		&job.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	if err := json.Unmarshal(requestJSON, &job.Request); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal export request: %w", err)
+	}
+	job.Rows = int(rows.Int64)
+	job.Error = reason.String
+
+	return &job, nil
+}
+
+// WriteChunk stores the next part of a running job's file
+func (r *PostgresExportJobRepository) WriteChunk(ctx context.Context, tenantID, jobID string, seq int, data []byte) error {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO export_chunks (job_id, seq, data, tenant_id)
+		SELECT job_id, $3, $4, tenant_id FROM export_jobs
+		WHERE job_id = $1 AND tenant_id = $2 AND status = 'RUNNING'
+	`, jobID, tenantID, seq, data)
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	// The job is gone when an erasure or purge deleted it mid-export
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("export job not running: %s", jobID)
+	}
+	return nil
+}
+
+// Content streams the file of a succeeded, unexpired job to w one chunk at a time
+func (r *PostgresExportJobRepository) Content(ctx context.Context, tenantID, jobID string, w io.Writer) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.data FROM export_chunks c
+		JOIN export_jobs j ON j.job_id = c.job_id
+		WHERE c.job_id = $1
+		  AND c.tenant_id = $2
+		  AND j.status = 'SUCCEEDED'
+		  AND j.expires_at > NOW()
+		ORDER BY c.seq
+	`, jobID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+	defer rows.Close()
+
+	found := false
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return fmt.Errorf("failed to read export: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		found = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("export %w: %s", ErrNotFound, jobID)
+	}
+	return nil
+}
+
+// Complete marks the job succeeded once every chunk is written; the file is kept for
+// retention from now on
+func (r *PostgresExportJobRepository) Complete(ctx context.Context, tenantID, jobID string, rows int, retention time.Duration) error {
+	return r.finish(ctx, tenantID, jobID, `
+		UPDATE export_jobs
+		SET status = 'SUCCEEDED', row_count = $3, completed_at = NOW(),
+		    expires_at = NOW() + $4 * INTERVAL '1 second'
+		WHERE job_id = $1 AND tenant_id = $2 AND status = 'RUNNING'
+	`, rows, retention.Seconds())
+}
+
+// Fail marks the job failed with the reason shown to the caller and drops the chunks
+// written so far
+func (r *PostgresExportJobRepository) Fail(ctx context.Context, tenantID, jobID, reason string) error {
+	return r.finish(ctx, tenantID, jobID, `
+		WITH dropped AS (
+			DELETE FROM export_chunks WHERE job_id = $1 AND tenant_id = $2
+		)
+		UPDATE export_jobs
+		SET status = 'FAILED', error = $3, completed_at = NOW()
+		WHERE job_id = $1 AND tenant_id = $2 AND status = 'RUNNING'
+	`, reason)
+}
+
+// DeleteExpired deletes the tenant's expired jobs with their files and returns how
+// many it deleted
+func (r *PostgresExportJobRepository) DeleteExpired(ctx context.Context, tenantID string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM export_jobs WHERE tenant_id = $1 AND expires_at <= NOW()
+	`, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired exports: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (r *PostgresExportJobRepository) finish(ctx context.Context, tenantID, jobID, query string, args ...interface{}) error {
+	res, err := r.db.ExecContext(ctx, query, append([]interface{}{jobID, tenantID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to finish export job: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("export job not running: %s", jobID)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/lib/pq"
+)
+
+// responsePartitionPrefix names monthly partitions, e.g. survey_responses_p202401
+const responsePartitionPrefix = "survey_responses_p"
+
+// PartitionName returns the name of the survey_responses partition holding month
+func PartitionName(month time.Time) string {
+	return responsePartitionPrefix + month.UTC().Format("200601")
+}
+
+// PartitionRepository manages the monthly partitions of survey_responses
+type PartitionRepository interface {
+	List(ctx context.Context) ([]models.ResponsePartition, error)
+	Create(ctx context.Context, month time.Time) error
+	Attach(ctx context.Context, month time.Time) error
+	Detach(ctx context.Context, month time.Time) error
+	Tenants(ctx context.Context, month time.Time) ([]string, error)
+	Responses(ctx context.Context, month time.Time, tenantID string) ([]models.Response, error)
+	Drop(ctx context.Context, month time.Time) error
+}
+
+// PostgresPartitionRepository implements PartitionRepository
+type PostgresPartitionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPartitionRepository(db *sql.DB) *PostgresPartitionRepository {
+	return &PostgresPartitionRepository{db: db}
+}
+
+// List returns attached and detached monthly partitions, oldest first
+func (r *PostgresPartitionRepository) List(ctx context.Context) ([]models.ResponsePartition, error) {
+	return listPartitions(ctx, r.db)
+}
+
+// listPartitions lists the monthly partitions through q, so transactions can see the
+// partitions they run against
+func listPartitions(ctx context.Context, q queryer) ([]models.ResponsePartition, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.relname,
+		       EXISTS (
+		           SELECT 1 FROM pg_inherits i
+		           WHERE i.inhrelid = c.oid
+		             AND i.inhparent = 'survey_responses'::regclass
+		       )
+		FROM pg_class c
+		WHERE c.relkind = 'r'
+		  AND c.relname ~ ('^' || $1 || '[0-9]{6}$')
+		  AND pg_table_is_visible(c.oid)
+		ORDER BY c.relname
+	`, responsePartitionPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []models.ResponsePartition
+	for rows.Next() {
+		var p models.ResponsePartition
+		if err := rows.Scan(&p.Name, &p.Attached); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		month, err := time.Parse("200601", strings.TrimPrefix(p.Name, responsePartitionPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected partition name %q: %w", p.Name, err)
+		}
+		p.Month = month
+		partitions = append(partitions, p)
+	}
+
+	return partitions, rows.Err()
+}
+
+// Create adds the partition for month if it does not exist. Indexes defined on
+// survey_responses are created on it automatically.
+func (r *PostgresPartitionRepository) Create(ctx context.Context, month time.Time) error {
+	from, to := partitionBounds(month)
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF survey_responses FOR VALUES FROM (%s) TO (%s)`,
+		pq.QuoteIdentifier(PartitionName(month)), from, to))
+	if err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", PartitionName(month), err)
+	}
+	return nil
+}
+
+// Attach re-attaches a detached partition. A matching CHECK constraint is added first
+// so Postgres can skip scanning the table; missing indexes are built during the attach.
+func (r *PostgresPartitionRepository) Attach(ctx context.Context, month time.Time) error {
+	name := PartitionName(month)
+	table := pq.QuoteIdentifier(name)
+	check := pq.QuoteIdentifier(name + "_bounds")
+	from, to := partitionBounds(month)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin attach: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT %s CHECK (submitted_at IS NOT NULL AND submitted_at >= %s AND submitted_at < %s)`,
+			table, check, from, to),
+		fmt.Sprintf(`ALTER TABLE survey_responses ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s)`, table, from, to),
+		fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT %s`, table, check),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to attach partition %s: %w", name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit attach of %s: %w", name, err)
+	}
+	return nil
+}
+
+// Detach removes a partition from survey_responses without blocking queries; the
+// table and its indexes are kept. CONCURRENTLY cannot run inside a transaction.
+func (r *PostgresPartitionRepository) Detach(ctx context.Context, month time.Time) error {
+	name := PartitionName(month)
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(
+		`ALTER TABLE survey_responses DETACH PARTITION %s CONCURRENTLY`, pq.QuoteIdentifier(name)))
+	if err != nil {
+		return fmt.Errorf("failed to detach partition %s: %w", name, err)
+	}
+	return nil
+}
+
+// Tenants returns the tenants with responses in month's partition, attached or not
+func (r *PostgresPartitionRepository) Tenants(ctx context.Context, month time.Time) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT DISTINCT tenant_id FROM %s ORDER BY tenant_id`, pq.QuoteIdentifier(PartitionName(month))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants of %s: %w", PartitionName(month), err)
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, tenantID)
+	}
+	return tenants, rows.Err()
+}
+
+// Responses reads one tenant's rows straight from month's partition. Detached
+// partitions are plain tables without RLS, so the tenant filter is explicit.
+func (r *PostgresPartitionRepository) Responses(ctx context.Context, month time.Time, tenantID string) ([]models.Response, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE tenant_id = $1
+		ORDER BY submitted_at, response_id
+	`, responseColumns, pq.QuoteIdentifier(PartitionName(month))), tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PartitionName(month), err)
+	}
+	defer rows.Close()
+
+	var responses []models.Response
+	for rows.Next() {
+		resp, err := scanResponseRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		responses = append(responses, *resp)
+	}
+	return responses, rows.Err()
+}
+
+// Drop deletes a detached partition. Attached partitions are refused so live data is
+// never dropped by accident. The month's response_rollups rows are deleted in the same
+// transaction: rollups only count rows in survey_responses, so archived months leave
+// them together with their partition and verify and rebuild stay consistent.
+func (r *PostgresPartitionRepository) Drop(ctx context.Context, month time.Time) error {
+	name := PartitionName(month)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin drop of %s: %w", name, err)
+	}
+	defer tx.Rollback()
+
+	var attached bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+		    SELECT 1 FROM pg_inherits
+		    WHERE inhrelid = to_regclass($1)
+		      AND inhparent = 'survey_responses'::regclass
+		)
+	`, name).Scan(&attached)
+	if err != nil {
+		return fmt.Errorf("failed to check partition %s: %w", name, err)
+	}
+	if attached {
+		return fmt.Errorf("refusing to drop attached partition %s", name)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP TABLE `+pq.QuoteIdentifier(name)); err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM response_rollups WHERE month = $1::date
+	`, month.UTC().Format("2006-01-02")); err != nil {
+		return fmt.Errorf("failed to drop rollups of %s: %w", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit drop of %s: %w", name, err)
+	}
+	return nil
+}
+
+// partitionBounds returns the quoted [from, to) range literals of month's partition
+func partitionBounds(month time.Time) (string, string) {
+	m := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	const layout = "2006-01-02 15:04:05"
+	return pq.QuoteLiteral(m.Format(layout)), pq.QuoteLiteral(m.AddDate(0, 1, 0).Format(layout))
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// SummaryViewName is the materialized view dashboard count queries are served from
+const SummaryViewName = "mv_department_summary"
+
+// ViewRefreshRepository refreshes the dashboard materialized views and tracks freshness
+type ViewRefreshRepository interface {
+	Refresh(ctx context.Context) error
+	LastRefresh(ctx context.Context, viewName string) (*models.ViewRefresh, error)
+	PendingChanges(ctx context.Context, since time.Time) (int, error)
+}
+
+// LeaderLock elects a single leader among replicas
+type LeaderLock interface {
+	TryAcquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// PostgresViewRefreshRepository implements ViewRefreshRepository
+type PostgresViewRefreshRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresViewRefreshRepository(db *sql.DB) *PostgresViewRefreshRepository {
+	return &PostgresViewRefreshRepository{db: db}
+}
+
+// Refresh recomputes the views; refresh_dashboard_views records the refresh time
+func (r *PostgresViewRefreshRepository) Refresh(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `SELECT refresh_dashboard_views()`); err != nil {
+		return fmt.Errorf("failed to refresh dashboard views: %w", err)
+	}
+	return nil
+}
+
+// LastRefresh returns the view's last refresh, or nil if it was never refreshed
+func (r *PostgresViewRefreshRepository) LastRefresh(ctx context.Context, viewName string) (*models.ViewRefresh, error) {
+	var refresh models.ViewRefresh
+	err := r.db.QueryRowContext(ctx, `
+		SELECT view_name, data_as_of, refreshed_at, duration_ms
+		FROM view_refreshes
+		WHERE view_name = $1
+	`, viewName).Scan(&refresh.ViewName, &refresh.DataAsOf, &refresh.RefreshedAt, &refresh.DurationMS)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get view refresh: %w", err)
+	}
+
+	return &refresh, nil
+}
+
+// PendingChanges counts responses to open surveys submitted or changed after since
+func (r *PostgresViewRefreshRepository) PendingChanges(ctx context.Context, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM survey_responses r
+		JOIN surveys s ON s.survey_id = r.survey_id AND s.tenant_id = r.tenant_id
+		WHERE s.status = 'OPEN'
+		  AND COALESCE(r.updated_at, r.submitted_at) > $1
+	`, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending changes: %w", err)
+	}
+	return n, nil
+}
+
+// AdvisoryLock is a session-level Postgres advisory lock held on a dedicated connection.
+// The lock is released when the connection closes, so a crashed leader loses it.
+type AdvisoryLock struct {
+	db   *sql.DB
+	name string
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewAdvisoryLock(db *sql.DB, name string) *AdvisoryLock {
+	return &AdvisoryLock{db: db, name: name}
+}
+
+// TryAcquire takes the lock without waiting. It returns true while this process holds it.
+func (l *AdvisoryLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		// Connection lost, and the lock with it
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, l.name).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Release gives up the lock if held
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, l.name); err != nil {
+		return fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"dashboard-case-study/pkg/models"
@@ -17,7 +18,18 @@ import (
 type ResponseRepository interface {
 	Create(ctx context.Context, response *models.Response) error
 	GetByID(ctx context.Context, responseID string) (*models.Response, error)
+	GetByIdempotencyKey(ctx context.Context, tenantID, key string) (*models.Response, error)
+	Update(ctx context.Context, response *models.Response, changedBy string) error
+	SetStatus(ctx context.Context, tenantID, responseID string, status models.ResponseStatus, changedBy, reason string) error
+	GetVersions(ctx context.Context, tenantID, responseID string) ([]models.ResponseVersion, error)
 	Query(ctx context.Context, query models.DashboardQuery) ([]models.Response, error)
+	Iterate(ctx context.Context, query models.DashboardQuery) (ResponseIterator, error)
+	Aggregate(ctx context.Context, query models.DashboardQuery) ([]models.GroupResult, error)
+	DistinctValues(ctx context.Context, query models.DashboardQuery, field string) ([]string, error)
+	SnapshotKeys(ctx context.Context, query models.DashboardQuery) ([]string, error)
+	Comments(ctx context.Context, query models.DashboardQuery, questionID, search string, limit int) (int, []string, error)
+	AggregateSummary(ctx context.Context, query models.DashboardQuery) ([]models.GroupResult, error)
+	Explain(ctx context.Context, query models.DashboardQuery, path models.QueryPath) ([]models.ExplainedStatement, error)
 }
 
 // EmployeeRepository handles employee data
@@ -32,6 +44,7 @@ type OrgRepository interface {
 	GetUnitAtTime(ctx context.Context, unitID string, asOf time.Time) (*models.OrgUnit, error)
 	GetMapping(ctx context.Context, sourceUnitID string) (*models.OrgUnitMapping, error)
 	FindMappingsByTarget(ctx context.Context, targetUnitID string) ([]models.OrgUnitMapping, error)
+	FindCurrentUnitsByName(ctx context.Context, unitName string) ([]models.OrgUnit, error)
 }
 
 // PostgresResponseRepository implements ResponseRepository
@@ -58,12 +71,19 @@ func (r *PostgresResponseRepository) Create(ctx context.Context, response *model
 	query := `
 		INSERT INTO survey_responses (
 			response_id, survey_id, employee_id, submitted_at, 
-			snapshot_core, version_id, answers, tenant_id
-		) VALUES ($1, $2, $3, NOW(), $4, $5, $6, $7)
-		RETURNING submitted_at, created_at
+			snapshot_core, version_id, answers, tenant_id,
+			idempotency_key, one_per_employee
+		) VALUES ($1, $2, $3, NOW(), $4, $5, $6, $7, NULLIF($8, ''), $9)
+		RETURNING submitted_at, created_at, status, revision
 	`
 
-	err = r.db.QueryRowContext(ctx, query,
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin create: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query,
 		response.ResponseID,
 		response.SurveyID,
 		response.EmployeeID,
@@ -71,27 +91,486 @@ func (r *PostgresResponseRepository) Create(ctx context.Context, response *model
 		response.VersionID,
 		answersJSON,
 		response.TenantID,
-	).Scan(&response.SubmittedAt, &response.CreatedAt)
-
+		response.IdempotencyKey,
+		response.OnePerEmployee,
+	).Scan(&response.SubmittedAt, &response.CreatedAt, &response.Status, &response.Revision)
+
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		switch pqErr.Constraint {
+		case "idx_responses_idempotency_key":
+			return ErrIdempotencyKeyExists
+		case "idx_responses_one_per_employee":
+			return ErrDuplicateResponse
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create response: %w", err)
 	}
 
+	// The insert trigger claimed the key; record which submission it belongs to
+	if response.IdempotencyKey != "" {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE response_idempotency_keys SET request_hash = $3
+			WHERE tenant_id = $1 AND idempotency_key = $2
+		`, response.TenantID, response.IdempotencyKey, response.RequestHash)
+		if err != nil {
+			return fmt.Errorf("failed to store idempotency key: %w", err)
+		}
+	}
+
+	if err := adjustRollup(ctx, tx, response.TenantID, response.ResponseID, 1); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit response: %w", err)
+	}
 	return nil
 }
 
+// responseColumns is the column list scanned by scanResponseRow
+const responseColumns = `
+		response_id, survey_id, employee_id, submitted_at,
+		snapshot_core, version_id, answers, tenant_id, created_at,
+		status, revision, updated_at`
+
 func (r *PostgresResponseRepository) GetByID(ctx context.Context, responseID string) (*models.Response, error) {
-	query := `
-		SELECT response_id, survey_id, employee_id, submitted_at,
-		       snapshot_core, version_id, answers, tenant_id, created_at
+	query := `SELECT ` + responseColumns + `
+		FROM survey_responses
+		WHERE response_id = $1
+	`
+
+	response, err := scanResponseRow(r.db.QueryRowContext(ctx, query, responseID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("response %w: %s", ErrNotFound, responseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	return response, nil
+}
+
+// GetByIdempotencyKey returns the response stored under key with its RequestHash, or
+// nil if none
+func (r *PostgresResponseRepository) GetByIdempotencyKey(ctx context.Context, tenantID, key string) (*models.Response, error) {
+	query := `SELECT ` + responseColumns + `
 		FROM survey_responses
+		WHERE tenant_id = $1
+		  AND idempotency_key = $2
+	`
+
+	response, err := scanResponseRow(r.db.QueryRowContext(ctx, query, tenantID, key))
+	if err == sql.ErrNoRows {
+		return nil, nil // No prior submission
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	response.IdempotencyKey = key
+
+	var hash sql.NullString
+	err = r.db.QueryRowContext(ctx, `
+		SELECT request_hash FROM response_idempotency_keys
+		WHERE tenant_id = $1 AND idempotency_key = $2
+	`, tenantID, key).Scan(&hash)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	response.RequestHash = hash.String
+	return response, nil
+}
+
+// Update stores the current answers (and snapshot) of response as a new revision,
+// archiving the previous revision in survey_response_versions
+func (r *PostgresResponseRepository) Update(ctx context.Context, response *models.Response, changedBy string) error {
+	snapshotJSON, err := json.Marshal(response.SnapshotCore)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot_core: %w", err)
+	}
+
+	answersJSON, err := json.Marshal(response.Answers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal answers: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin update: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := archiveRevision(ctx, tx, response.TenantID, response.ResponseID, changedBy); err != nil {
+		return err
+	}
+
+	// A recaptured snapshot may move the response to another unit or grade
+	if err := adjustRollup(ctx, tx, response.TenantID, response.ResponseID, -1); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE survey_responses
+		SET answers = $3,
+		    snapshot_core = $4,
+		    version_id = $5,
+		    revision = revision + 1,
+		    updated_at = NOW()
+		WHERE response_id = $1
+		  AND tenant_id = $2
+		  AND status = 'ACTIVE'
+		RETURNING revision, updated_at
+	`,
+		response.ResponseID,
+		response.TenantID,
+		answersJSON,
+		snapshotJSON,
+		response.VersionID,
+	).Scan(&response.Revision, &response.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrResponseNotActive
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update response: %w", err)
+	}
+
+	if err := adjustRollup(ctx, tx, response.TenantID, response.ResponseID, 1); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit update: %w", err)
+	}
+	return nil
+}
+
+// SetStatus withdraws or voids an active response, archiving its last revision
+func (r *PostgresResponseRepository) SetStatus(ctx context.Context, tenantID, responseID string, status models.ResponseStatus, changedBy, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin status change: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := archiveRevision(ctx, tx, tenantID, responseID, changedBy); err != nil {
+		return err
+	}
+
+	// Rollups count active responses only
+	if err := adjustRollup(ctx, tx, tenantID, responseID, -1); err != nil {
+		return err
+	}
+
+	n, err := execCount(ctx, tx, `
+		UPDATE survey_responses
+		SET status = $3,
+		    status_reason = $4,
+		    revision = revision + 1,
+		    updated_at = NOW()
+		WHERE response_id = $1
+		  AND tenant_id = $2
+		  AND status = 'ACTIVE'
+	`, responseID, tenantID, status, reason)
+	if err != nil {
+		return fmt.Errorf("failed to change response status: %w", err)
+	}
+	if n == 0 {
+		return ErrResponseNotActive
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit status change: %w", err)
+	}
+	return nil
+}
+
+// GetVersions returns the archived revisions of a response, oldest first
+func (r *PostgresResponseRepository) GetVersions(ctx context.Context, tenantID, responseID string) ([]models.ResponseVersion, error) {
+	query := `
+		SELECT response_id, revision, answers, snapshot_core, version_id,
+		       status, changed_by, changed_at
+		FROM survey_response_versions
 		WHERE response_id = $1
+		  AND tenant_id = $2
+		ORDER BY revision
 	`
 
+	rows, err := r.db.QueryContext(ctx, query, responseID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query response versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []models.ResponseVersion
+	for rows.Next() {
+		var v models.ResponseVersion
+		var snapshotJSON []byte
+		err := rows.Scan(
+			&v.ResponseID,
+			&v.Revision,
+			&v.Answers,
+			&snapshotJSON,
+			&v.VersionID,
+			&v.Status,
+			&v.ChangedBy,
+			&v.ChangedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan response version: %w", err)
+		}
+		if err := json.Unmarshal(snapshotJSON, &v.SnapshotCore); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal snapshot_core: %w", err)
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// archiveRevision copies the current row of a response into survey_response_versions
+func archiveRevision(ctx context.Context, tx *sql.Tx, tenantID, responseID, changedBy string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO survey_response_versions (
+			response_id, revision, answers, snapshot_core, version_id,
+			status, changed_by, tenant_id
+		)
+		SELECT response_id, revision, answers, snapshot_core, version_id,
+		       status, $3, tenant_id
+		FROM survey_responses
+		WHERE response_id = $1
+		  AND tenant_id = $2
+		FOR UPDATE
+	`, responseID, tenantID, changedBy)
+	if err != nil {
+		return fmt.Errorf("failed to archive response revision: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresResponseRepository) Query(ctx context.Context, q models.DashboardQuery) ([]models.Response, error) {
+	baseQuery, args, err := buildResponsesQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	db, done, err := r.reader(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rows, err := db.QueryContext(ctx, baseQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query responses: %w", err)
+	}
+	defer rows.Close()
+
+	var responses []models.Response
+	for rows.Next() {
+		resp, err := scanResponseRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		responses = append(responses, *resp)
+	}
+
+	return responses, nil
+}
+
+// buildResponsesQuery renders the query behind Query: the newest 1000 matching responses
+func buildResponsesQuery(q models.DashboardQuery) (string, []interface{}, error) {
+	where, args := buildWhere(q)
+	from := responseSource(q)
+	return `SELECT ` + responseColumns + `
+		FROM ` + from + `
+	` + where + " ORDER BY submitted_at DESC LIMIT 1000", args, nil
+}
+
+// ResponseIterator reads query results one row at a time, so memory does not grow
+// with the result. Close must be called once iteration stops.
+type ResponseIterator interface {
+	Next() bool
+	Response() *models.Response
+	Err() error
+	Close() error
+}
+
+// Iterate returns every response the query selects, newest first, without the row
+// limit of Query. Cancelling ctx stops the underlying query; Err then reports it.
+func (r *PostgresResponseRepository) Iterate(ctx context.Context, q models.DashboardQuery) (ResponseIterator, error) {
+	where, args := buildWhere(q)
+	from := responseSource(q)
+
+	db, done, err := r.reader(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT `+responseColumns+`
+		FROM `+from+`
+	`+where+` ORDER BY submitted_at DESC, response_id`, args...)
+	if err != nil {
+		done()
+		return nil, fmt.Errorf("failed to query responses: %w", err)
+	}
+	return &rowsResponseIterator{rows: rows, done: done}, nil
+}
+
+// rowsResponseIterator implements ResponseIterator over *sql.Rows
+type rowsResponseIterator struct {
+	rows    *sql.Rows
+	done    func() // Ends the staging transaction, if any
+	current *models.Response
+	err     error
+}
+
+func (it *rowsResponseIterator) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	it.current, it.err = scanResponseRow(it.rows)
+	if it.err != nil {
+		it.err = fmt.Errorf("failed to scan row: %w", it.err)
+		return false
+	}
+	return true
+}
+
+func (it *rowsResponseIterator) Response() *models.Response {
+	return it.current
+}
+
+func (it *rowsResponseIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+func (it *rowsResponseIterator) Close() error {
+	err := it.rows.Close()
+	it.done()
+	return err
+}
+
+// archivedColumns types responseColumns for the staging table of archived rows. Times
+// are timestamp like in survey_responses: timestamptz would turn the whole union into
+// timestamptz and break the UTC conversions applied to submitted_at.
+const archivedColumns = `
+		response_id text, survey_id text, employee_id text, submitted_at timestamp,
+		snapshot_core jsonb, version_id text, answers jsonb, tenant_id text, created_at timestamp,
+		status text, revision int, updated_at timestamp`
+
+// archivedTable is the temporary table archived rows are staged in
+const archivedTable = "archived_responses"
+
+// responseSource returns the relation dashboard queries read from: survey_responses,
+// or survey_responses plus the query's archived rows staged by reader. The union keeps
+// the name survey_responses so filters and aggregates apply unchanged.
+func responseSource(q models.DashboardQuery) string {
+	if len(q.Archived) == 0 {
+		return "survey_responses"
+	}
+	return fmt.Sprintf(`(
+			SELECT %s FROM survey_responses
+			UNION ALL
+			SELECT %s FROM %s
+		) AS survey_responses`, responseColumns, responseColumns, archivedTable)
+}
+
+// queryer runs reads on the database or inside a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// reader returns where to run statements built with responseSource(q). Archived rows
+// are copied into a temporary table inside a new transaction, so they reach Postgres
+// through COPY rather than as one parameter; done rolls the transaction back, which
+// drops the table. Without archived rows the database is used directly.
+func (r *PostgresResponseRepository) reader(ctx context.Context, q models.DashboardQuery) (queryer, func(), error) {
+	if len(q.Archived) == 0 {
+		return r.db, func() {}, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin archive staging: %w", err)
+	}
+	done := func() { tx.Rollback() }
+
+	if err := stageArchived(ctx, tx, q.Archived); err != nil {
+		done()
+		return nil, nil, err
+	}
+	return tx, done, nil
+}
+
+// stageArchived creates the staging table in tx and copies the rows into it
+func stageArchived(ctx context.Context, tx *sql.Tx, responses []models.Response) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE `+archivedTable+` (`+archivedColumns+`) ON COMMIT DROP`); err != nil {
+		return fmt.Errorf("failed to create archive staging table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(archivedTable,
+		"response_id", "survey_id", "employee_id", "submitted_at", "snapshot_core", "version_id",
+		"answers", "tenant_id", "created_at", "status", "revision", "updated_at"))
+	if err != nil {
+		return fmt.Errorf("failed to start archive staging: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, resp := range responses {
+		row, err := archivedRow(resp)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("failed to stage archived response %s: %w", resp.ResponseID, err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to stage archived responses: %w", err)
+	}
+
+	// Planner statistics for the staged rows
+	if _, err := tx.ExecContext(ctx, `ANALYZE `+archivedTable); err != nil {
+		return fmt.Errorf("failed to analyze archive staging table: %w", err)
+	}
+	return nil
+}
+
+// archivedRow returns the staged values of an archived response in COPY column order
+func archivedRow(resp models.Response) ([]interface{}, error) {
+	snapshot, err := json.Marshal(resp.SnapshotCore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot of %s: %w", resp.ResponseID, err)
+	}
+	var updatedAt interface{}
+	if resp.UpdatedAt != nil {
+		updatedAt = utcWallClock(*resp.UpdatedAt)
+	}
+	return []interface{}{
+		resp.ResponseID, resp.SurveyID, resp.EmployeeID, utcWallClock(resp.SubmittedAt), string(snapshot), resp.VersionID,
+		string(resp.Answers), resp.TenantID, utcWallClock(resp.CreatedAt), string(resp.Status), resp.Revision, updatedAt,
+	}, nil
+}
+
+// utcWallClock formats t as the UTC wall-clock time survey_responses stores, so the
+// session TimeZone never shifts a staged value
+func utcWallClock(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.999999")
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanResponseRow(row rowScanner) (*models.Response, error) {
 	var response models.Response
 	var snapshotJSON, answersJSON []byte
 
-	err := r.db.QueryRowContext(ctx, query, responseID).Scan(
+	err := row.Scan(
 		&response.ResponseID,
 		&response.SurveyID,
 		&response.EmployeeID,
@@ -101,13 +580,12 @@ func (r *PostgresResponseRepository) GetByID(ctx context.Context, responseID str
 		&answersJSON,
 		&response.TenantID,
 		&response.CreatedAt,
+		&response.Status,
+		&response.Revision,
+		&response.UpdatedAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("response not found: %s", responseID)
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get response: %w", err)
+		return nil, err
 	}
 
 	// Unmarshal JSONB fields
@@ -121,66 +599,116 @@ func (r *PostgresResponseRepository) GetByID(ctx context.Context, responseID str
 	return &response, nil
 }
 
-func (r *PostgresResponseRepository) Query(ctx context.Context, q models.DashboardQuery) ([]models.Response, error) {
-	// Build dynamic query based on filters
-	baseQuery := `
-		SELECT response_id, survey_id, employee_id, submitted_at,
-		       snapshot_core, version_id, answers, tenant_id, created_at
-		FROM survey_responses
-		WHERE tenant_id = $1
-		  AND submitted_at BETWEEN $2 AND $3
-	`
+// buildWhere renders the tenant, time range and snapshot_core filters of q
+func buildWhere(q models.DashboardQuery) (string, []interface{}) {
+	return appendWhere(q, nil)
+}
+
+// appendWhere renders the filter clause with placeholders numbered after the given args
+func appendWhere(q models.DashboardQuery, args []interface{}) (string, []interface{}) {
+	bind := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := fmt.Sprintf(`
+		WHERE tenant_id = %s
+		  AND submitted_at BETWEEN %s AND %s
+	`, bind(q.TenantID), bind(q.TimeRange.From), bind(q.TimeRange.To))
 
-	args := []interface{}{q.TenantID, q.TimeRange.From, q.TimeRange.To}
-	argIndex := 4
+	// Withdrawn and voided responses are excluded unless explicitly requested
+	if !q.IncludeWithdrawn {
+		where += " AND status = 'ACTIVE'"
+	}
+
+	if q.SurveyID != "" {
+		where += " AND survey_id = " + bind(q.SurveyID)
+	}
 
 	// Add JSONB filters
 	for field, value := range q.Filters {
-		baseQuery += fmt.Sprintf(" AND snapshot_core->>$%d = $%d", argIndex, argIndex+1)
-		args = append(args, field, fmt.Sprintf("%v", value))
-		argIndex += 2
-	}
+		if strings.HasPrefix(field, models.AnswerFieldPrefix) {
+			where += " AND " + answerPredicate(models.AnswerFilter{
+				QuestionID: strings.TrimPrefix(field, models.AnswerFieldPrefix),
+				Op:         models.FilterOpEq,
+				Value:      value,
+			}, bind)
+			continue
+		}
 
-	baseQuery += " ORDER BY submitted_at DESC LIMIT 1000"
+		if list, ok := filterList(value); ok {
+			where += fmt.Sprintf(" AND snapshot_core->>%s = ANY(%s)", bind(field), bind(pq.Array(list)))
+		} else {
+			where += fmt.Sprintf(" AND snapshot_core->>%s = %s", bind(field), bind(fmt.Sprintf("%v", value)))
+		}
+	}
 
-	rows, err := r.db.QueryContext(ctx, baseQuery, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query responses: %w", err)
+	for _, f := range q.AnswerFilters {
+		where += " AND " + answerPredicate(f, bind)
 	}
-	defer rows.Close()
 
-	var responses []models.Response
-	for rows.Next() {
-		var resp models.Response
-		var snapshotJSON, answersJSON []byte
+	return where, args
+}
 
-		err := rows.Scan(
-			&resp.ResponseID,
-			&resp.SurveyID,
-			&resp.EmployeeID,
-			&resp.SubmittedAt,
-			&snapshotJSON,
-			&resp.VersionID,
-			&answersJSON,
-			&resp.TenantID,
-			&resp.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+// filterList returns the values of a filter matching any of several values. Filters
+// decoded from JSON hold []interface{}; the service builds []string.
+func filterList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			list[i] = fmt.Sprintf("%v", item)
 		}
+		return list, true
+	}
+	return nil, false
+}
 
-		// Unmarshal JSONB
-		if err := json.Unmarshal(snapshotJSON, &resp.SnapshotCore); err != nil {
-			return nil, err
-		}
-		if err := json.Unmarshal(answersJSON, &resp.Answers); err != nil {
-			return nil, err
+// answerPredicate renders one answer filter. Equality and containment are written as
+// answers @> {...} so the GIN index on answers serves them; range comparisons go
+// through answer_numeric, which per-question expression indexes are built on.
+func answerPredicate(f models.AnswerFilter, bind func(interface{}) string) string {
+	question := bind(f.QuestionID)
+
+	switch f.Op {
+	case models.FilterOpEq:
+		return fmt.Sprintf("answers @> jsonb_build_object(%s::text, %s::jsonb)", question, bind(jsonValue(f.Value)))
+	case models.FilterOpNe:
+		return fmt.Sprintf("(answers ? %s AND NOT answers @> jsonb_build_object(%s::text, %s::jsonb))",
+			question, question, bind(jsonValue(f.Value)))
+	case models.FilterOpContains:
+		return fmt.Sprintf("answers @> jsonb_build_object(%s::text, jsonb_build_array(%s::jsonb))", question, bind(jsonValue(f.Value)))
+	case models.FilterOpIn:
+		values, _ := f.Value.([]interface{})
+		candidates := make([]string, len(values))
+		for i, v := range values {
+			candidates[i] = jsonValue(v)
 		}
+		return fmt.Sprintf("answers->%s = ANY(%s::jsonb[])", question, bind(pq.Array(candidates)))
+	}
 
-		responses = append(responses, resp)
+	ops := map[models.FilterOp]string{
+		models.FilterOpLt:  "<",
+		models.FilterOpLte: "<=",
+		models.FilterOpGt:  ">",
+		models.FilterOpGte: ">=",
 	}
+	op, ok := ops[f.Op]
+	if !ok {
+		return "FALSE" // Unknown operators match nothing; the service rejects them first
+	}
+	return fmt.Sprintf("answer_numeric(answers, %s) %s %s", question, op, bind(f.Value))
+}
 
-	return responses, nil
+// jsonValue encodes a filter value as a JSON literal for comparison with jsonb
+func jsonValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(b)
 }
 
 // PostgresEmployeeRepository implements EmployeeRepository
@@ -215,7 +743,7 @@ func (r *PostgresEmployeeRepository) GetByID(ctx context.Context, employeeID str
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("employee not found: %s", employeeID)
+		return nil, fmt.Errorf("employee %w: %s", ErrNotFound, employeeID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get employee: %w", err)
@@ -310,7 +838,7 @@ func (r *PostgresOrgRepository) scanOrgUnit(ctx context.Context, query string, a
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("org unit not found")
+		return nil, fmt.Errorf("org unit %w", ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get org unit: %w", err)
@@ -388,6 +916,45 @@ func (r *PostgresOrgRepository) FindMappingsByTarget(ctx context.Context, target
 	return mappings, nil
 }
 
+// FindCurrentUnitsByName returns today's units with the given name
+func (r *PostgresOrgRepository) FindCurrentUnitsByName(ctx context.Context, unitName string) ([]models.OrgUnit, error) {
+	query := `
+		SELECT unit_id, unit_name, parent_unit_id, valid_from, valid_to,
+		       is_active, tenant_id, path
+		FROM org_units_history
+		WHERE unit_name = $1
+		  AND valid_to IS NULL
+		ORDER BY unit_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, unitName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query org units: %w", err)
+	}
+	defer rows.Close()
+
+	var units []models.OrgUnit
+	for rows.Next() {
+		var unit models.OrgUnit
+		err := rows.Scan(
+			&unit.UnitID,
+			&unit.UnitName,
+			&unit.ParentUnitID,
+			&unit.ValidFrom,
+			&unit.ValidTo,
+			&unit.IsActive,
+			&unit.TenantID,
+			&unit.Path,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan org unit: %w", err)
+		}
+		units = append(units, unit)
+	}
+
+	return units, rows.Err()
+}
+
 // GenerateID generates a new UUID
 func GenerateID() string {
 	return uuid.New().String()
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestArchivedTimeBucketsInTenantZone tests that archived rows are staged as UTC wall-clock
+// timestamps, so a local time bucket converts them like live rows
+func TestArchivedTimeBucketsInTenantZone(t *testing.T) {
+	singapore, err := time.LoadLocation("Asia/Singapore")
+	assert.NoError(t, err)
+
+	// 1 Feb 02:00 in Singapore is still January in UTC
+	submitted := time.Date(2024, 2, 1, 2, 0, 0, 0, singapore)
+	archived := models.Response{ResponseID: "r1", SubmittedAt: submitted, CreatedAt: submitted}
+	q := models.DashboardQuery{
+		TimeRange:  models.TimeRange{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		GroupBy:    []string{models.GroupByTimeBucket},
+		TimeBucket: models.TimeBucketMonth,
+		TimeZone:   "Asia/Singapore",
+		Archived:   []models.Response{archived},
+	}
+
+	row, err := archivedRow(archived)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-31 18:00:00", row[3])
+	assert.Equal(t, "2024-01-31 18:00:00", row[8])
+	assert.Nil(t, row[11])
+	assert.NotContains(t, archivedColumns, "timestamptz")
+
+	sql, args, err := buildAggregateQuery(q)
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "UNION ALL")
+	assert.Contains(t, sql, "FROM "+archivedTable)
+	// The union stays timestamp, so this reads submitted_at as UTC before localising it
+	assert.Contains(t, sql, "(submitted_at AT TIME ZONE 'UTC') AT TIME ZONE")
+	assert.Contains(t, args, "Asia/Singapore")
+	assert.NotContains(t, sql, "timestamptz")
+}
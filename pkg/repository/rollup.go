@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// rollupSource groups active responses by the rollup key; $1 optionally limits it to a tenant
+const rollupSource = `
+		SELECT tenant_id, survey_id,
+		       COALESCE(snapshot_core->>'unit_id', '') AS unit_id,
+		       COALESCE(snapshot_core->>'performance_grade', '') AS grade,
+		       DATE_TRUNC('month', submitted_at)::date AS month,
+		       COUNT(*) AS response_count
+		FROM survey_responses
+		WHERE status = 'ACTIVE'
+		  AND ($1 = '' OR tenant_id = $1)
+		GROUP BY 1, 2, 3, 4, 5`
+
+// RollupRepository recomputes and checks the incrementally maintained rollups
+type RollupRepository interface {
+	Rebuild(ctx context.Context, tenantID string) (int, error)
+	Verify(ctx context.Context, tenantID string) ([]models.RollupMismatch, error)
+}
+
+// PostgresRollupRepository implements RollupRepository
+type PostgresRollupRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRollupRepository(db *sql.DB) *PostgresRollupRepository {
+	return &PostgresRollupRepository{db: db}
+}
+
+// adjustRollup adds delta to the rollup row of an active response within tx.
+// Inactive or missing responses are not counted and leave the rollups unchanged.
+func adjustRollup(ctx context.Context, tx *sql.Tx, tenantID, responseID string, delta int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO response_rollups (tenant_id, survey_id, unit_id, grade, month, response_count)
+		SELECT tenant_id, survey_id,
+		       COALESCE(snapshot_core->>'unit_id', ''),
+		       COALESCE(snapshot_core->>'performance_grade', ''),
+		       DATE_TRUNC('month', submitted_at)::date,
+		       $3
+		FROM survey_responses
+		WHERE response_id = $1
+		  AND tenant_id = $2
+		  AND status = 'ACTIVE'
+		ON CONFLICT (tenant_id, survey_id, unit_id, grade, month) DO UPDATE
+		SET response_count = response_rollups.response_count + EXCLUDED.response_count,
+		    updated_at = NOW()
+	`, responseID, tenantID, delta)
+	if err != nil {
+		return fmt.Errorf("failed to update response rollup: %w", err)
+	}
+	return nil
+}
+
+// Rebuild recomputes the rollups of one tenant, or all tenants if tenantID is empty.
+// Writers are blocked for the duration, so no increment is lost or counted twice.
+func (r *PostgresRollupRepository) Rebuild(ctx context.Context, tenantID string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin rollup rebuild: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Conflicts with the ROW EXCLUSIVE lock adjustRollup takes, so in-flight writes
+	// either commit before the recount sees them or update the rollups after it
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE response_rollups IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return 0, fmt.Errorf("failed to lock rollups: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM response_rollups WHERE ($1 = '' OR tenant_id = $1)
+	`, tenantID); err != nil {
+		return 0, fmt.Errorf("failed to clear rollups: %w", err)
+	}
+
+	n, err := execCount(ctx, tx, `
+		INSERT INTO response_rollups (tenant_id, survey_id, unit_id, grade, month, response_count)
+	`+rollupSource, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recompute rollups: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit rollup rebuild: %w", err)
+	}
+	return n, nil
+}
+
+// Verify compares the rollups with a recount of survey_responses. Rows that fell to
+// zero after withdrawals are equivalent to missing rows. Archived months have no
+// rollups, since dropping a partition deletes them; a partition that is detached but
+// not yet archived reports its months as mismatches until it is archived or attached.
+func (r *PostgresRollupRepository) Verify(ctx context.Context, tenantID string) ([]models.RollupMismatch, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH expected AS (`+rollupSource+`),
+		actual AS (
+			SELECT tenant_id, survey_id, unit_id, grade, month, response_count
+			FROM response_rollups
+			WHERE ($1 = '' OR tenant_id = $1)
+		)
+		SELECT COALESCE(e.tenant_id, a.tenant_id),
+		       COALESCE(e.survey_id, a.survey_id),
+		       COALESCE(e.unit_id, a.unit_id),
+		       COALESCE(e.grade, a.grade),
+		       COALESCE(e.month, a.month),
+		       COALESCE(e.response_count, 0),
+		       COALESCE(a.response_count, 0)
+		FROM expected e
+		FULL OUTER JOIN actual a USING (tenant_id, survey_id, unit_id, grade, month)
+		WHERE COALESCE(e.response_count, 0) <> COALESCE(a.response_count, 0)
+		ORDER BY 1, 2, 5, 3, 4
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify rollups: %w", err)
+	}
+	defer rows.Close()
+
+	mismatches := []models.RollupMismatch{}
+	for rows.Next() {
+		var m models.RollupMismatch
+		if err := rows.Scan(&m.TenantID, &m.SurveyID, &m.UnitID, &m.Grade, &m.Month, &m.Expected, &m.Actual); err != nil {
+			return nil, fmt.Errorf("failed to scan rollup mismatch: %w", err)
+		}
+		mismatches = append(mismatches, m)
+	}
+
+	return mismatches, rows.Err()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/lib/pq"
+)
+
+// summaryColumns maps the dimensions mv_department_summary can answer to its columns
+var summaryColumns = map[string]string{
+	"department":             "department",
+	"performance_grade":      "grade",
+	models.GroupBySurvey:     "survey_id",
+	models.GroupByTimeBucket: "to_char(month, 'YYYY-MM-DD')",
+}
+
+// AggregateSummary answers a count-by query from mv_department_summary. The caller must
+// have checked that the query qualifies: HISTORICAL, active responses only, month-aligned
+// time range, and only view columns in its filters and group-bys.
+func (r *PostgresResponseRepository) AggregateSummary(ctx context.Context, q models.DashboardQuery) ([]models.GroupResult, error) {
+	query, args, err := buildSummaryQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query department summary: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []models.GroupResult
+	for rows.Next() {
+		keys := make([]sql.NullString, len(q.GroupBy))
+		var count sql.NullInt64
+		dest := make([]interface{}, 0, len(keys)+1)
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		if err := rows.Scan(append(dest, &count)...); err != nil {
+			return nil, fmt.Errorf("failed to scan summary row: %w", err)
+		}
+
+		group := models.GroupResult{Key: make(map[string]string, len(q.GroupBy)), Count: int(count.Int64)}
+		for i, field := range q.GroupBy {
+			group.Key[field] = keys[i].String // NULL → ""
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// buildSummaryQuery renders a count-by query over mv_department_summary
+func buildSummaryQuery(q models.DashboardQuery) (string, []interface{}, error) {
+	args := []interface{}{q.TenantID, q.TimeRange.From, q.TimeRange.To}
+	bind := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := "WHERE tenant_id = $1 AND month BETWEEN $2 AND $3"
+	if q.SurveyID != "" {
+		where += " AND survey_id = " + bind(q.SurveyID)
+	}
+	for field, value := range q.Filters {
+		column, ok := summaryColumns[field]
+		if !ok {
+			return "", nil, fmt.Errorf("mv_department_summary has no column for %q", field)
+		}
+		if list, ok := filterList(value); ok {
+			where += fmt.Sprintf(" AND %s = ANY(%s)", column, bind(pq.Array(list)))
+		} else {
+			where += fmt.Sprintf(" AND %s = %s", column, bind(fmt.Sprintf("%v", value)))
+		}
+	}
+
+	var cols, groupCols []string
+	for i, field := range q.GroupBy {
+		column, ok := summaryColumns[field]
+		if !ok {
+			return "", nil, fmt.Errorf("mv_department_summary has no column for %q", field)
+		}
+		cols = append(cols, fmt.Sprintf("%s AS g%d", column, i))
+		groupCols = append(groupCols, fmt.Sprintf("g%d", i))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM mv_department_summary
+		%s
+	`, strings.Join(append(cols, "SUM(response_count)::int"), ", "), where)
+	if len(groupCols) > 0 {
+		cols := strings.Join(groupCols, ", ")
+		query += fmt.Sprintf(" GROUP BY %s ORDER BY %s", cols, cols)
+	}
+
+	return query, args, nil
+}
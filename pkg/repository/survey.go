@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres SQLSTATE for unique constraint violations
+const uniqueViolation = "23505"
+
+var (
+	// ErrIdempotencyKeyExists is returned when a response was already stored under the key
+	ErrIdempotencyKeyExists = NewKindError(ErrConflict, "idempotency key already used")
+	// ErrDuplicateResponse is returned when an employee answers a single-response survey twice
+	ErrDuplicateResponse = NewKindError(ErrConflict, "employee already responded to this survey")
+	// ErrResponseNotActive is returned when editing a missing, withdrawn or voided response
+	ErrResponseNotActive = NewKindError(ErrConflict, "response not found or no longer active")
+	// ErrSurveyExists is returned when creating a survey whose ID is taken
+	ErrSurveyExists = NewKindError(ErrConflict, "survey already exists")
+	// ErrSurveyStateChanged is returned when a transition's expected current state no longer holds
+	ErrSurveyStateChanged = NewKindError(ErrConflict, "survey state changed concurrently")
+)
+
+// SurveyRepository handles survey definitions
+type SurveyRepository interface {
+	Create(ctx context.Context, survey *models.Survey) error
+	Update(ctx context.Context, survey *models.Survey) error
+	Delete(ctx context.Context, tenantID, surveyID string) error
+	GetByID(ctx context.Context, tenantID, surveyID string) (*models.Survey, error)
+	List(ctx context.Context, tenantID string) ([]models.Survey, error)
+	Launch(ctx context.Context, survey *models.Survey) (int, error)
+	SetStatus(ctx context.Context, tenantID, surveyID string, from, to models.SurveyStatus) error
+	IsEligible(ctx context.Context, tenantID, surveyID, employeeID string) (bool, error)
+	GetParticipation(ctx context.Context, tenantID, surveyID string) (*models.Participation, error)
+	GetParticipationByUnit(ctx context.Context, tenantID, surveyID string) ([]models.UnitResponseCount, error)
+}
+
+// PostgresSurveyRepository implements SurveyRepository
+type PostgresSurveyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSurveyRepository(db *sql.DB) *PostgresSurveyRepository {
+	return &PostgresSurveyRepository{db: db}
+}
+
+const surveyColumns = `
+		survey_id, title, questions, single_response, resnapshot_on_edit,
+		status, opens_at, closes_at, audience, launched_at,
+		tenant_id, created_at, updated_at`
+
+func (r *PostgresSurveyRepository) Create(ctx context.Context, survey *models.Survey) error {
+	questionsJSON, err := json.Marshal(survey.Questions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal questions: %w", err)
+	}
+
+	audienceJSON, err := json.Marshal(survey.Audience)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audience: %w", err)
+	}
+
+	// New surveys always start as drafts
+	query := `
+		INSERT INTO surveys (
+			survey_id, title, questions, single_response, resnapshot_on_edit,
+			status, opens_at, closes_at, audience, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, 'DRAFT', $6, $7, $8, $9)
+		RETURNING status, created_at, updated_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		survey.SurveyID,
+		survey.Title,
+		questionsJSON,
+		survey.SingleResponse,
+		survey.ResnapshotOnEdit,
+		survey.OpensAt,
+		survey.ClosesAt,
+		audienceJSON,
+		survey.TenantID,
+	).Scan(&survey.Status, &survey.CreatedAt, &survey.UpdatedAt)
+
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return ErrSurveyExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create survey: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresSurveyRepository) Update(ctx context.Context, survey *models.Survey) error {
+	questionsJSON, err := json.Marshal(survey.Questions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal questions: %w", err)
+	}
+
+	audienceJSON, err := json.Marshal(survey.Audience)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audience: %w", err)
+	}
+
+	// Status and launch time only change through Launch/SetStatus
+	query := `
+		UPDATE surveys
+		SET title = $3,
+		    questions = $4,
+		    single_response = $5,
+		    resnapshot_on_edit = $6,
+		    opens_at = $7,
+		    closes_at = $8,
+		    audience = $9,
+		    updated_at = NOW()
+		WHERE survey_id = $1
+		  AND tenant_id = $2
+		RETURNING status, launched_at, created_at, updated_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		survey.SurveyID,
+		survey.TenantID,
+		survey.Title,
+		questionsJSON,
+		survey.SingleResponse,
+		survey.ResnapshotOnEdit,
+		survey.OpensAt,
+		survey.ClosesAt,
+		audienceJSON,
+	).Scan(&survey.Status, &survey.LaunchedAt, &survey.CreatedAt, &survey.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("survey %w: %s", ErrNotFound, survey.SurveyID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update survey: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresSurveyRepository) Delete(ctx context.Context, tenantID, surveyID string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM surveys WHERE survey_id = $1 AND tenant_id = $2
+	`, surveyID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete survey: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("survey %w: %s", ErrNotFound, surveyID)
+	}
+	return nil
+}
+
+// GetByID returns the survey, or nil if it has no stored definition
+func (r *PostgresSurveyRepository) GetByID(ctx context.Context, tenantID, surveyID string) (*models.Survey, error) {
+	query := `SELECT ` + surveyColumns + `
+		FROM surveys
+		WHERE survey_id = $1
+		  AND tenant_id = $2
+	`
+
+	survey, err := scanSurveyRow(r.db.QueryRowContext(ctx, query, surveyID, tenantID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Legacy survey without definition
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get survey: %w", err)
+	}
+
+	return survey, nil
+}
+
+func (r *PostgresSurveyRepository) List(ctx context.Context, tenantID string) ([]models.Survey, error) {
+	query := `SELECT ` + surveyColumns + `
+		FROM surveys
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query surveys: %w", err)
+	}
+	defer rows.Close()
+
+	var surveys []models.Survey
+	for rows.Next() {
+		survey, err := scanSurveyRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan survey: %w", err)
+		}
+		surveys = append(surveys, *survey)
+	}
+
+	return surveys, rows.Err()
+}
+
+// Launch opens a draft survey and captures its eligible population from the audience:
+// listed employees plus everyone currently in the audience's org subtrees. The population
+// is frozen so later transfers don't change who was invited. Returns the population size.
+func (r *PostgresSurveyRepository) Launch(ctx context.Context, survey *models.Survey) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin launch: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE surveys
+		SET status = 'OPEN',
+		    launched_at = NOW(),
+		    updated_at = NOW()
+		WHERE survey_id = $1
+		  AND tenant_id = $2
+		  AND status = 'DRAFT'
+		RETURNING status, launched_at, updated_at
+	`, survey.SurveyID, survey.TenantID).Scan(&survey.Status, &survey.LaunchedAt, &survey.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrSurveyStateChanged
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to launch survey: %w", err)
+	}
+
+	invited, err := execCount(ctx, tx, `
+		INSERT INTO survey_eligibility (survey_id, employee_id, unit_id, unit_path, tenant_id)
+		SELECT $1, e.employee_id, e.unit_id, u.path, e.tenant_id
+		FROM employees e
+		LEFT JOIN org_units_history u
+		       ON u.unit_id = e.unit_id
+		      AND u.valid_to IS NULL
+		WHERE e.tenant_id = $2
+		  AND (
+		        e.employee_id = ANY($3)
+		     OR ARRAY(
+		            SELECT root.path FROM org_units_history root
+		            WHERE root.unit_id = ANY($4)
+		              AND root.valid_to IS NULL
+		        ) @> u.path
+		  )
+		ON CONFLICT DO NOTHING
+	`, survey.SurveyID, survey.TenantID, pq.Array(survey.Audience.EmployeeIDs), pq.Array(survey.Audience.UnitIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to capture eligible population: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit launch: %w", err)
+	}
+	return invited, nil
+}
+
+// SetStatus moves a survey from one state to another, failing if it is no longer in from
+func (r *PostgresSurveyRepository) SetStatus(ctx context.Context, tenantID, surveyID string, from, to models.SurveyStatus) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE surveys
+		SET status = $4,
+		    updated_at = NOW()
+		WHERE survey_id = $1
+		  AND tenant_id = $2
+		  AND status = $3
+	`, surveyID, tenantID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to change survey status: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSurveyStateChanged
+	}
+	return nil
+}
+
+// IsEligible reports whether the employee is in the survey's captured population
+func (r *PostgresSurveyRepository) IsEligible(ctx context.Context, tenantID, surveyID, employeeID string) (bool, error) {
+	var eligible bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM survey_eligibility
+			WHERE survey_id = $1
+			  AND tenant_id = $2
+			  AND employee_id = $3
+		)
+	`, surveyID, tenantID, employeeID).Scan(&eligible)
+	if err != nil {
+		return false, fmt.Errorf("failed to check eligibility: %w", err)
+	}
+	return eligible, nil
+}
+
+// GetParticipation counts invited employees and how many of them have an active response
+func (r *PostgresSurveyRepository) GetParticipation(ctx context.Context, tenantID, surveyID string) (*models.Participation, error) {
+	participation := models.Participation{SurveyID: surveyID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE EXISTS (
+		           SELECT 1 FROM survey_responses sr
+		           WHERE sr.survey_id = el.survey_id
+		             AND sr.tenant_id = el.tenant_id
+		             AND sr.employee_id = el.employee_id
+		             AND sr.status = 'ACTIVE'
+		       ))
+		FROM survey_eligibility el
+		WHERE el.survey_id = $1
+		  AND el.tenant_id = $2
+	`, surveyID, tenantID).Scan(&participation.Invited, &participation.Responded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participation: %w", err)
+	}
+
+	if participation.Invited > 0 {
+		participation.Rate = float64(participation.Responded) / float64(participation.Invited)
+	}
+	return &participation, nil
+}
+
+// GetParticipationByUnit counts invited and responded employees per unit held at launch
+func (r *PostgresSurveyRepository) GetParticipationByUnit(ctx context.Context, tenantID, surveyID string) ([]models.UnitResponseCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(el.unit_id, ''),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE EXISTS (
+		           SELECT 1 FROM survey_responses sr
+		           WHERE sr.survey_id = el.survey_id
+		             AND sr.tenant_id = el.tenant_id
+		             AND sr.employee_id = el.employee_id
+		             AND sr.status = 'ACTIVE'
+		       ))
+		FROM survey_eligibility el
+		WHERE el.survey_id = $1
+		  AND el.tenant_id = $2
+		GROUP BY 1
+		ORDER BY 1
+	`, surveyID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query participation by unit: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.UnitResponseCount
+	for rows.Next() {
+		var c models.UnitResponseCount
+		if err := rows.Scan(&c.UnitID, &c.Invited, &c.Responded); err != nil {
+			return nil, fmt.Errorf("failed to scan participation row: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+func scanSurveyRow(row rowScanner) (*models.Survey, error) {
+	var survey models.Survey
+	var questionsJSON, audienceJSON []byte
+
+	err := row.Scan(
+		&survey.SurveyID,
+		&survey.Title,
+		&questionsJSON,
+		&survey.SingleResponse,
+		&survey.ResnapshotOnEdit,
+		&survey.Status,
+		&survey.OpensAt,
+		&survey.ClosesAt,
+		&audienceJSON,
+		&survey.LaunchedAt,
+		&survey.TenantID,
+		&survey.CreatedAt,
+		&survey.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(questionsJSON, &survey.Questions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal questions: %w", err)
+	}
+	if err := json.Unmarshal(audienceJSON, &survey.Audience); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audience: %w", err)
+	}
+
+	return &survey, nil
+}
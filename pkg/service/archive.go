@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// ArchiveService moves old survey_responses partitions to cold storage
+type ArchiveService struct {
+	partitionRepo repository.PartitionRepository
+	archive       repository.ArchiveStore
+	now           func() time.Time
+}
+
+func NewArchiveService(partitionRepo repository.PartitionRepository, archive repository.ArchiveStore) *ArchiveService {
+	return &ArchiveService{
+		partitionRepo: partitionRepo,
+		archive:       archive,
+		now:           time.Now,
+	}
+}
+
+// Archive exports every detached partition, and every partition older than olderThan
+// months (counting the current one), to one file per tenant, then drops it. A
+// partition is only dropped once each file reads back with the expected row count.
+// olderThan <= 0 archives detached partitions only. Rows are anonymised as they are
+// exported (see anonymiseArchived), so erasure never has to rewrite archive files.
+func (s *ArchiveService) Archive(ctx context.Context, olderThan int) (*models.ArchiveReport, error) {
+	partitions, err := s.partitionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ArchiveReport{Months: []models.ArchivedMonth{}}
+	cutoff := monthStart(s.now().UTC()).AddDate(0, 1-olderThan, 0)
+
+	for _, p := range partitions {
+		if p.Attached && (olderThan <= 0 || !p.Month.Before(cutoff)) {
+			continue
+		}
+
+		// Detach first so rows cannot change while they are exported
+		if p.Attached {
+			if err := s.partitionRepo.Detach(ctx, p.Month); err != nil {
+				return report, err
+			}
+		}
+
+		archived, err := s.archiveMonth(ctx, p)
+		if err != nil {
+			return report, err
+		}
+		if err := s.partitionRepo.Drop(ctx, p.Month); err != nil {
+			return report, err
+		}
+		report.Months = append(report.Months, *archived)
+	}
+
+	return report, nil
+}
+
+// archiveMonth writes and verifies one file per tenant in a detached partition
+func (s *ArchiveService) archiveMonth(ctx context.Context, p models.ResponsePartition) (*models.ArchivedMonth, error) {
+	tenants, err := s.partitionRepo.Tenants(ctx, p.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	archived := &models.ArchivedMonth{Partition: p.Name, Month: p.Month, Tenants: len(tenants)}
+	for _, tenantID := range tenants {
+		responses, err := s.partitionRepo.Responses(ctx, p.Month, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		responses = anonymiseArchived(responses)
+		if err := s.archive.Write(ctx, tenantID, p.Month, responses); err != nil {
+			return nil, err
+		}
+
+		written, err := s.archive.Read(ctx, tenantID, p.Month)
+		if err != nil {
+			return nil, err
+		}
+		if len(written) != len(responses) {
+			return nil, fmt.Errorf("archive of %s for tenant %s has %d rows, expected %d",
+				p.Name, tenantID, len(written), len(responses))
+		}
+		archived.Responses += len(responses)
+	}
+	return archived, nil
+}
+
+// archiveStrippedKeys are the snapshot keys erasure removes from a subject's responses
+var archiveStrippedKeys = []string{"employee_name", "employee_email", "employee_pseudonym", "age", "tenure"}
+
+// anonymiseArchived returns the rows as they are archived: already in the state erasure
+// would leave them in. Employee and version IDs are replaced with a tombstone derived
+// from the response ID, and identifying snapshot keys are removed. Archived rows stay
+// usable for aggregates but can no longer be traced to, or erased for, an employee.
+func anonymiseArchived(responses []models.Response) []models.Response {
+	anonymised := make([]models.Response, len(responses))
+	for i, r := range responses {
+		tombstone := "archived_" + r.ResponseID
+		r.EmployeeID = tombstone
+		r.VersionID = tombstone
+
+		snapshot := make(map[string]interface{}, len(r.SnapshotCore))
+		for k, v := range r.SnapshotCore {
+			snapshot[k] = v
+		}
+		for _, k := range archiveStrippedKeys {
+			delete(snapshot, k)
+		}
+		r.SnapshotCore = snapshot
+		anonymised[i] = r
+	}
+	return anonymised
+}
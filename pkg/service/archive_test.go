@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockArchiveStore is a mock implementation for testing
+type MockArchiveStore struct {
+	mock.Mock
+}
+
+func (m *MockArchiveStore) Write(ctx context.Context, tenantID string, month time.Time, responses []models.Response) error {
+	args := m.Called(ctx, tenantID, month, responses)
+	return args.Error(0)
+}
+
+func (m *MockArchiveStore) Read(ctx context.Context, tenantID string, month time.Time) ([]models.Response, error) {
+	args := m.Called(ctx, tenantID, month)
+	return args.Get(0).([]models.Response), args.Error(1)
+}
+
+func (m *MockArchiveStore) Scan(ctx context.Context, tenantID string, month time.Time, filter repository.ArchiveFilter, limit int) ([]models.Response, error) {
+	args := m.Called(ctx, tenantID, month, filter, limit)
+	return args.Get(0).([]models.Response), args.Error(1)
+}
+
+func (m *MockArchiveStore) Months(ctx context.Context, tenantID string) ([]time.Time, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]time.Time), args.Error(1)
+}
+
+// TestArchiveOldPartitions tests that old and detached partitions are exported, verified and dropped
+func TestArchiveOldPartitions(t *testing.T) {
+	mockRepo := new(MockPartitionRepository)
+	mockArchive := new(MockArchiveStore)
+	archiveSvc := NewArchiveService(mockRepo, mockArchive)
+	archiveSvc.now = func() time.Time { return time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	mockRepo.On("List", ctx).Return([]models.ResponsePartition{
+		partition(2024, 1, false),
+		partition(2024, 2, true),
+		partition(2024, 3, true),
+		partition(2024, 6, true),
+	}, nil)
+
+	rows := []models.Response{{ResponseID: "r1", TenantID: "t1"}, {ResponseID: "r2", TenantID: "t1"}}
+	for _, month := range []time.Time{jan, feb} {
+		mockRepo.On("Tenants", ctx, month).Return([]string{"t1"}, nil)
+		mockRepo.On("Responses", ctx, month, "t1").Return(rows, nil)
+		mockArchive.On("Write", ctx, "t1", month, mock.Anything).Return(nil)
+		mockArchive.On("Read", ctx, "t1", month).Return(rows, nil)
+		mockRepo.On("Drop", ctx, month).Return(nil)
+	}
+	mockRepo.On("Detach", ctx, feb).Return(nil)
+
+	// March and June are within the last four months
+	report, err := archiveSvc.Archive(ctx, 4)
+
+	assert.NoError(t, err)
+	assert.Len(t, report.Months, 2)
+	assert.Equal(t, "survey_responses_p202402", report.Months[1].Partition)
+	assert.Equal(t, 2, report.Months[1].Responses)
+	mockRepo.AssertExpectations(t)
+	mockArchive.AssertExpectations(t)
+}
+
+// TestArchiveKeepsPartitionOnShortWrite tests that a partition is not dropped when its archive is incomplete
+func TestArchiveKeepsPartitionOnShortWrite(t *testing.T) {
+	mockRepo := new(MockPartitionRepository)
+	mockArchive := new(MockArchiveStore)
+	archiveSvc := NewArchiveService(mockRepo, mockArchive)
+	ctx := context.Background()
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []models.Response{{ResponseID: "r1"}, {ResponseID: "r2"}}
+	mockRepo.On("List", ctx).Return([]models.ResponsePartition{partition(2024, 1, false)}, nil)
+	mockRepo.On("Tenants", ctx, jan).Return([]string{"t1"}, nil)
+	mockRepo.On("Responses", ctx, jan, "t1").Return(rows, nil)
+	mockArchive.On("Write", ctx, "t1", jan, mock.Anything).Return(nil)
+	mockArchive.On("Read", ctx, "t1", jan).Return(rows[:1], nil)
+
+	_, err := archiveSvc.Archive(ctx, 0)
+
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Drop", ctx, jan)
+}
+
+// TestArchiveAnonymisesRows tests that archive files never hold employee or version IDs
+// or the snapshot keys erasure removes
+func TestArchiveAnonymisesRows(t *testing.T) {
+	mockRepo := new(MockPartitionRepository)
+	mockArchive := new(MockArchiveStore)
+	archiveSvc := NewArchiveService(mockRepo, mockArchive)
+	ctx := context.Background()
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []models.Response{{
+		ResponseID:   "r1",
+		TenantID:     "t1",
+		EmployeeID:   "emp_123",
+		VersionID:    "emp_123_1704067200",
+		SnapshotCore: map[string]interface{}{"unit_id": "unit_sales", "age": 34, "age_band": "30-39", "employee_name": "Ada"},
+	}}
+	mockRepo.On("List", ctx).Return([]models.ResponsePartition{partition(2024, 1, false)}, nil)
+	mockRepo.On("Tenants", ctx, jan).Return([]string{"t1"}, nil)
+	mockRepo.On("Responses", ctx, jan, "t1").Return(rows, nil)
+	mockArchive.On("Write", ctx, "t1", jan, mock.Anything).Return(nil)
+	mockArchive.On("Read", ctx, "t1", jan).Return(rows, nil)
+	mockRepo.On("Drop", ctx, jan).Return(nil)
+
+	_, err := archiveSvc.Archive(ctx, 0)
+	assert.NoError(t, err)
+
+	written := mockArchive.Calls[0].Arguments.Get(3).([]models.Response)
+	assert.Len(t, written, 1)
+	assert.Equal(t, "archived_r1", written[0].EmployeeID)
+	assert.Equal(t, "archived_r1", written[0].VersionID)
+	assert.Equal(t, map[string]interface{}{"unit_id": "unit_sales", "age_band": "30-39"}, written[0].SnapshotCore)
+	assert.NotContains(t, fmt.Sprint(written), "emp_123")
+
+	// The partition's own rows are left untouched
+	assert.Equal(t, "emp_123", rows[0].EmployeeID)
+	assert.Equal(t, 34, rows[0].SnapshotCore["age"])
+}
+
+// TestQueryReadsArchivedMonths tests that archived rows in the time range are passed to the query
+func TestQueryReadsArchivedMonths(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockArchive := new(MockArchiveStore)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+	service.SetArchiveStore(mockArchive)
+
+	ctx := context.Background()
+	query := summaryQuery()
+	query.TenantID = "t1"
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	inRange := models.Response{ResponseID: "r1", SubmittedAt: jan.AddDate(0, 0, 3)}
+	mockArchive.On("Months", ctx, "t1").Return([]time.Time{jan.AddDate(-1, 0, 0), jan}, nil)
+	filter := repository.ArchiveFilter{From: query.TimeRange.From, To: query.TimeRange.To, ActiveOnly: true}
+	mockArchive.On("Scan", ctx, "t1", jan, filter, MaxArchivedResponses).Return([]models.Response{inRange}, nil)
+
+	archived := mock.MatchedBy(func(q models.DashboardQuery) bool {
+		return len(q.Archived) == 1 && q.Archived[0].ResponseID == "r1"
+	})
+	mockResponseRepo.On("Query", ctx, archived).Return([]models.Response{inRange}, nil)
+	mockResponseRepo.On("Aggregate", ctx, archived).Return([]models.GroupResult{
+		{Key: map[string]string{"performance_grade": "A", models.GroupByTimeBucket: "2024-01-01"}, Count: 12},
+	}, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	// Archived rows are not in the materialized view, so the base table path is used
+	assert.Equal(t, models.QueryPathBaseTable, result.Metadata.Path)
+	assert.Equal(t, 1, result.Metadata.ArchivedResponses)
+	mockResponseRepo.AssertNotCalled(t, "AggregateSummary", ctx, mock.Anything)
+	mockArchive.AssertNotCalled(t, "Scan", ctx, "t1", jan.AddDate(-1, 0, 0), mock.Anything, mock.Anything)
+}
+
+// TestQueryArchivedLimit tests that a query restoring too many archived rows is rejected
+func TestQueryArchivedLimit(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockArchive := new(MockArchiveStore)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+	service.SetArchiveStore(mockArchive)
+
+	ctx := context.Background()
+	query := summaryQuery()
+	query.TenantID = "t1"
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := jan.AddDate(0, 1, 0)
+	mockArchive.On("Months", ctx, "t1").Return([]time.Time{jan, feb}, nil)
+	mockArchive.On("Scan", ctx, "t1", jan, mock.Anything, MaxArchivedResponses).
+		Return(make([]models.Response, MaxArchivedResponses-1), nil)
+	// Only the remaining budget is requested from the next month
+	mockArchive.On("Scan", ctx, "t1", feb, mock.Anything, 1).Return(make([]models.Response, 2), nil)
+
+	_, err := service.Query(ctx, query)
+
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	mockResponseRepo.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+}
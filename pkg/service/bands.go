@@ -0,0 +1,74 @@
+package service
+
+import (
+	"time"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// DefaultBandConfig returns the standard demographic bands used for reporting
+func DefaultBandConfig() models.BandConfig {
+	return models.BandConfig{
+		AgeBands: []models.Band{
+			{Label: "<25", Min: 0, Max: bound(25)},
+			{Label: "25-34", Min: 25, Max: bound(35)},
+			{Label: "35-44", Min: 35, Max: bound(45)},
+			{Label: "45-54", Min: 45, Max: bound(55)},
+			{Label: "55+", Min: 55},
+		},
+		TenureBands: []models.Band{
+			{Label: "<1y", Min: 0, Max: bound(1)},
+			{Label: "1-3y", Min: 1, Max: bound(3)},
+			{Label: "3-5y", Min: 3, Max: bound(5)},
+			{Label: "5-10y", Min: 5, Max: bound(10)},
+			{Label: "10y+", Min: 10},
+		},
+	}
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+// bandFor returns the label of the first band containing value, or "unknown"
+func bandFor(bands []models.Band, value float64) string {
+	for _, b := range bands {
+		if b.Contains(value) {
+			return b.Label
+		}
+	}
+	return "unknown"
+}
+
+// calculateAge returns completed years between birthDate and asOf.
+// A 29 February birthday is reached on 1 March in non-leap years.
+func calculateAge(birthDate, asOf time.Time) int {
+	age := asOf.Year() - birthDate.Year()
+	if asOf.Month() < birthDate.Month() ||
+		(asOf.Month() == birthDate.Month() && asOf.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// calculateTenure returns tenure in years, truncated to 1 decimal, from completed calendar months
+func calculateTenure(hireDate, asOf time.Time) float64 {
+	months := completedMonths(hireDate, asOf)
+	if months < 0 {
+		return 0
+	}
+	return float64(months*10/12) / 10
+}
+
+// completedMonths counts whole calendar months between from and to
+func completedMonths(from, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() < from.Day() && !isLastDayOfMonth(to) {
+		months--
+	}
+	return months
+}
+
+func isLastDayOfMonth(t time.Time) bool {
+	return t.AddDate(0, 0, 1).Month() != t.Month()
+}
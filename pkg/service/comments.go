@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"dashboard-case-study/pkg/models"
+)
+
+const (
+	defaultCommentLimit = 50
+	maxCommentLimit     = 200
+)
+
+// Comments returns a shuffled sample of free-text answers for the responses a query
+// selects. Comments are only released when at least the anonymity threshold of
+// respondents in the group answered, so a single comment cannot be attributed.
+func (s *DashboardService) Comments(ctx context.Context, cq models.CommentQuery) (*models.CommentResult, error) {
+	if cq.QuestionID == "" {
+		verr := &ValidationError{}
+		verr.Add("question_id", "required", "question_id is required")
+		return nil, verr
+	}
+
+	limit := cq.Limit
+	if limit <= 0 {
+		limit = defaultCommentLimit
+	}
+	if limit > maxCommentLimit {
+		limit = maxCommentLimit
+	}
+
+	query, err := s.rowQuery(ctx, cq.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	total, comments, err := s.responseRepo.Comments(ctx, query, cq.QuestionID, strings.TrimSpace(cq.Search), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CommentResult{QuestionID: cq.QuestionID, Respondents: total, Comments: comments}
+	// Like suppressed counts elsewhere, the group size is withheld too
+	if total < s.anonymityThreshold || total == 0 {
+		result.Respondents = 0
+		result.Comments = []string{}
+		result.Suppressed = true
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestCommentsAboveThreshold tests that comments are returned for large enough groups
+func TestCommentsAboveThreshold(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+
+	ctx := context.Background()
+	cq := models.CommentQuery{
+		Query:      models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical},
+		QuestionID: "q9",
+		Search:     " workload ",
+	}
+
+	mockResponseRepo.On("Comments", ctx, mock.Anything, "q9", "workload", defaultCommentLimit).
+		Return(12, []string{"Workload is high", "Too much workload"}, nil)
+
+	result, err := service.Comments(ctx, cq)
+
+	assert.NoError(t, err)
+	assert.False(t, result.Suppressed)
+	assert.Equal(t, 12, result.Respondents)
+	assert.Len(t, result.Comments, 2)
+}
+
+// TestCommentsSuppressedBelowThreshold tests that small groups release no comments
+func TestCommentsSuppressedBelowThreshold(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+
+	ctx := context.Background()
+	cq := models.CommentQuery{
+		Query:      models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical},
+		QuestionID: "q9",
+		Limit:      1000,
+	}
+
+	mockResponseRepo.On("Comments", ctx, mock.Anything, "q9", "", maxCommentLimit).
+		Return(3, []string{"I am the only one in my team who works nights"}, nil)
+
+	result, err := service.Comments(ctx, cq)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Suppressed)
+	assert.Empty(t, result.Comments)
+	assert.Zero(t, result.Respondents)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// ErrInvalidComparison is returned when a comparison period selects nothing to compare
+var ErrInvalidComparison = repository.NewKindError(ErrInvalidRequest, "each comparison period needs a time_range or survey_id")
+
+// Compare runs the same query over the baseline and current periods and reports, per
+// group, both values, the delta and whether the change is statistically significant.
+// Org group-bys are mapped to today's structure in both periods, so a unit that was
+// renamed or merged in between lines up with its successor.
+func (s *DashboardService) Compare(ctx context.Context, cq models.ComparisonQuery) (*models.ComparisonResult, error) {
+	baselineQuery, err := periodQuery(cq.Query, cq.Baseline)
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %w", err)
+	}
+	currentQuery, err := periodQuery(cq.Query, cq.Current)
+	if err != nil {
+		return nil, fmt.Errorf("current: %w", err)
+	}
+
+	baseline, err := s.queryPeriod(ctx, baselineQuery)
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %w", err)
+	}
+	current, err := s.queryPeriod(ctx, currentQuery)
+	if err != nil {
+		return nil, fmt.Errorf("current: %w", err)
+	}
+
+	return &models.ComparisonResult{
+		Baseline: cq.Baseline,
+		Current:  cq.Current,
+		Groups:   compareGroups(cq.Query.Metrics, baseline.Groups, current.Groups),
+	}, nil
+}
+
+// periodQuery narrows the base query to one comparison period
+func periodQuery(base models.DashboardQuery, p models.ComparisonPeriod) (models.DashboardQuery, error) {
+	if p.TimeRange == nil && p.SurveyID == "" {
+		return base, ErrInvalidComparison
+	}
+
+	q := base
+	q.Filters = make(map[string]interface{}, len(base.Filters))
+	for k, v := range base.Filters {
+		q.Filters[k] = v
+	}
+	if p.TimeRange != nil {
+		q.TimeRange = *p.TimeRange
+	}
+	if p.SurveyID != "" {
+		q.SurveyID = p.SurveyID
+	}
+	return q, nil
+}
+
+// queryPeriod runs one side of a comparison. CURRENT mode already groups org units by
+// today's structure; other modes get the same mapping so both periods share keys.
+func (s *DashboardService) queryPeriod(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
+	if query.FilterMode == models.FilterModeCurrent || !groupsByOrg(query.GroupBy) {
+		return s.Query(ctx, query)
+	}
+
+	// Archived units need mapping too, so load them before building the mapping
+	if err := s.loadArchived(ctx, &query); err != nil {
+		return nil, err
+	}
+
+	unitMapping, err := s.currentUnitMapping(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	query.UnitMapping = unitMapping
+
+	result, err := s.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.labelCurrentDepartments(ctx, result.Groups)
+	return result, nil
+}
+
+// compareGroups joins baseline and current groups on their keys, keeping groups that
+// appear in only one period so disappearing and new units are still reported
+func compareGroups(specs []models.MetricSpec, baseline, current []models.GroupResult) []models.GroupComparison {
+	byKey := make(map[string]*models.GroupComparison)
+	var order []string
+
+	entry := func(g models.GroupResult) *models.GroupComparison {
+		k := groupKey(g.Key)
+		c, ok := byKey[k]
+		if !ok {
+			c = &models.GroupComparison{Key: g.Key, Metrics: make([]models.MetricComparison, len(specs))}
+			for i, spec := range specs {
+				c.Metrics[i] = models.MetricComparison{Type: spec.Type, QuestionID: spec.QuestionID}
+			}
+			byKey[k] = c
+			order = append(order, k)
+		}
+		return c
+	}
+
+	// SQL has already combined merged units into their successor's group
+	for _, g := range baseline {
+		c := entry(g)
+		c.BaselineCount = g.Count
+		for i := range g.Metrics {
+			if i < len(c.Metrics) {
+				m := g.Metrics[i]
+				c.Metrics[i].Baseline = &m
+			}
+		}
+	}
+	for _, g := range current {
+		c := entry(g)
+		c.CurrentCount = g.Count
+		for i := range g.Metrics {
+			if i < len(c.Metrics) {
+				m := g.Metrics[i]
+				c.Metrics[i].Current = &m
+			}
+		}
+	}
+
+	sort.Strings(order)
+	groups := make([]models.GroupComparison, 0, len(order))
+	for _, k := range order {
+		c := byKey[k]
+		for i := range c.Metrics {
+			compareMetric(&c.Metrics[i])
+		}
+		groups = append(groups, *c)
+	}
+	return groups
+}
+
+// groupKey renders a group key map in a stable order for joining
+func groupKey(key map[string]string) string {
+	fields := make([]string, 0, len(key))
+	for f := range key {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f)
+		b.WriteByte('=')
+		b.WriteString(key[f])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// compareMetric fills in the delta and a two-sided z-test of the difference between
+// two independent samples. Suppressed values are never compared.
+func compareMetric(c *models.MetricComparison) {
+	if c.Baseline == nil || c.Current == nil || c.Baseline.Value == nil || c.Current.Value == nil {
+		return
+	}
+
+	delta := *c.Current.Value - *c.Baseline.Value
+	c.Delta = &delta
+
+	se := math.Sqrt(math.Pow(standardError(*c.Baseline), 2) + math.Pow(standardError(*c.Current), 2))
+	if se == 0 {
+		return
+	}
+	z := math.Abs(delta) / se
+	p := math.Erfc(z / math.Sqrt2)
+	c.PValue = &p
+	c.Significant = z > z95
+}
+
+// standardError returns the standard error of a metric value on the scale it is reported in
+func standardError(m models.MetricValue) float64 {
+	n := float64(m.Respondents)
+	if n == 0 {
+		return 0
+	}
+	top := float64(m.Top) / n
+	bottom := float64(m.Bottom) / n
+
+	switch m.Type {
+	case models.MetricTypeENPS:
+		score := top - bottom
+		return math.Sqrt((top+bottom-score*score)/n) * 100
+	case models.MetricTypeFavourability:
+		return math.Sqrt(top*(1-top)/n) * 100
+	default:
+		return m.StdDev / math.Sqrt(n)
+	}
+}
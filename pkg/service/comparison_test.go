@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestCompareSurveyWaves tests per-group deltas between two survey waves
+func TestCompareSurveyWaves(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+
+	ctx := context.Background()
+	cq := models.ComparisonQuery{
+		Query: models.DashboardQuery{
+			TimeRange:  testTimeRange,
+			FilterMode: models.FilterModeHistorical,
+			GroupBy:    []string{"age_band"},
+			Metrics:    []models.MetricSpec{{Type: models.MetricTypeFavourability, QuestionID: "q1"}},
+		},
+		Baseline: models.ComparisonPeriod{SurveyID: "wave_1"},
+		Current:  models.ComparisonPeriod{SurveyID: "wave_2"},
+	}
+
+	wave := func(id string) interface{} {
+		return mock.MatchedBy(func(q models.DashboardQuery) bool { return q.SurveyID == id })
+	}
+	group := func(band string, n, top int) models.GroupResult {
+		return models.GroupResult{
+			Key:     map[string]string{"age_band": band},
+			Count:   n,
+			Metrics: []models.MetricValue{{Type: models.MetricTypeFavourability, QuestionID: "q1", Respondents: n, Top: top}},
+		}
+	}
+
+	mockResponseRepo.On("Query", ctx, mock.Anything).Return([]models.Response{}, nil)
+	mockResponseRepo.On("Aggregate", ctx, wave("wave_1")).Return([]models.GroupResult{
+		group("25-34", 200, 100),
+		group("35-44", 40, 20),
+	}, nil)
+	mockResponseRepo.On("Aggregate", ctx, wave("wave_2")).Return([]models.GroupResult{
+		group("25-34", 200, 140),
+		group("35-44", 40, 22),
+		group("45-54", 3, 3),
+	}, nil)
+
+	result, err := service.Compare(ctx, cq)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Groups, 3)
+
+	improved := result.Groups[0].Metrics[0]
+	assert.InDelta(t, 20, *improved.Delta, 0.001)
+	assert.True(t, improved.Significant)
+
+	flat := result.Groups[1].Metrics[0]
+	assert.InDelta(t, 5, *flat.Delta, 0.001)
+	assert.False(t, flat.Significant)
+
+	// New group, suppressed in the current wave: nothing to compare
+	added := result.Groups[2]
+	assert.Equal(t, 0, added.BaselineCount)
+	assert.Nil(t, added.Metrics[0].Baseline)
+	assert.Nil(t, added.Metrics[0].Delta)
+}
+
+// TestCompareRequiresPeriods tests that empty comparison periods are rejected
+func TestCompareRequiresPeriods(t *testing.T) {
+	service := NewDashboardService(new(MockResponseRepository), new(MockOrgRepository), new(MockSurveyRepository))
+
+	_, err := service.Compare(context.Background(), models.ComparisonQuery{
+		Query:    models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical},
+		Baseline: models.ComparisonPeriod{SurveyID: "wave_1"},
+	})
+
+	assert.ErrorIs(t, err, ErrInvalidComparison)
+}
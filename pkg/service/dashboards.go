@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// SavedDashboardService manages saved dashboards and runs their widgets
+type SavedDashboardService struct {
+	dashboardRepo repository.DashboardRepository
+	dashboardSvc  *DashboardService
+}
+
+func NewSavedDashboardService(dashboardRepo repository.DashboardRepository, dashboardSvc *DashboardService) *SavedDashboardService {
+	return &SavedDashboardService{
+		dashboardRepo: dashboardRepo,
+		dashboardSvc:  dashboardSvc,
+	}
+}
+
+// Create validates and stores a new dashboard
+func (s *SavedDashboardService) Create(ctx context.Context, dashboard *models.Dashboard) error {
+	if !HasPermission(ctx, PermissionManageDashboards) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageDashboards)
+	}
+	if err := validateDashboard(dashboard); err != nil {
+		return err
+	}
+	return s.dashboardRepo.Create(ctx, dashboard)
+}
+
+// Update validates and replaces an existing dashboard
+func (s *SavedDashboardService) Update(ctx context.Context, dashboard *models.Dashboard) error {
+	if !HasPermission(ctx, PermissionManageDashboards) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageDashboards)
+	}
+	if err := validateDashboard(dashboard); err != nil {
+		return err
+	}
+	return s.dashboardRepo.Update(ctx, dashboard)
+}
+
+// Delete removes a dashboard
+func (s *SavedDashboardService) Delete(ctx context.Context, tenantID, dashboardID string) error {
+	if !HasPermission(ctx, PermissionManageDashboards) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageDashboards)
+	}
+	return s.dashboardRepo.Delete(ctx, tenantID, dashboardID)
+}
+
+// Get returns a dashboard definition
+func (s *SavedDashboardService) Get(ctx context.Context, tenantID, dashboardID string) (*models.Dashboard, error) {
+	dashboard, err := s.dashboardRepo.GetByID(ctx, tenantID, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+	if dashboard == nil {
+		return nil, fmt.Errorf("dashboard %w: %s", repository.ErrNotFound, dashboardID)
+	}
+	return dashboard, nil
+}
+
+// List returns the tenant's dashboards
+func (s *SavedDashboardService) List(ctx context.Context, tenantID string) ([]models.Dashboard, error) {
+	return s.dashboardRepo.List(ctx, tenantID)
+}
+
+// Run executes every widget of a dashboard concurrently with the runtime overrides
+// applied. A widget that fails reports its error without failing the others.
+func (s *SavedDashboardService) Run(ctx context.Context, tenantID, dashboardID string, req models.DashboardRunRequest) (*models.DashboardRunResult, error) {
+	dashboard, err := s.Get(ctx, tenantID, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.WidgetResult, len(dashboard.Widgets))
+	var wg sync.WaitGroup
+	for i, widget := range dashboard.Widgets {
+		wg.Add(1)
+		go func(i int, widget models.Widget) {
+			defer wg.Done()
+
+			results[i].WidgetID = widget.WidgetID
+			result, err := s.dashboardSvc.Query(ctx, applyOverrides(widget.Query, tenantID, req))
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Result = result
+		}(i, widget)
+	}
+	wg.Wait()
+
+	return &models.DashboardRunResult{DashboardID: dashboard.DashboardID, Widgets: results}, nil
+}
+
+// applyOverrides returns the widget's query scoped to the tenant with runtime overrides
+func applyOverrides(query models.DashboardQuery, tenantID string, req models.DashboardRunRequest) models.DashboardQuery {
+	query.TenantID = tenantID
+
+	filters := make(map[string]interface{}, len(query.Filters)+len(req.Filters))
+	for k, v := range query.Filters {
+		filters[k] = v
+	}
+	for k, v := range req.Filters {
+		filters[k] = v
+	}
+	query.Filters = filters
+
+	if req.FilterMode != "" {
+		query.FilterMode = req.FilterMode
+	}
+	if req.TimeRange != nil {
+		query.TimeRange = *req.TimeRange
+	}
+	return query
+}
+
+// validateDashboard checks the dashboard and the query of each widget
+func validateDashboard(dashboard *models.Dashboard) error {
+	verr := &ValidationError{}
+	if dashboard.Title == "" {
+		verr.Add("title", "required", "title is required")
+	}
+
+	seen := make(map[string]bool)
+	for i, w := range dashboard.Widgets {
+		field := fmt.Sprintf("widgets[%d]", i)
+		if w.WidgetID == "" {
+			verr.Add(field+".widget_id", "required", "widget_id is required")
+		} else if seen[w.WidgetID] {
+			verr.Add(field+".widget_id", "duplicate", "widget_id %q is used twice", w.WidgetID)
+		}
+		seen[w.WidgetID] = true
+
+		validateQueryOptions(verr, field+".query.", w.Query)
+	}
+
+	return verr.OrNil()
+}
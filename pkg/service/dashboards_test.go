@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDashboardRepository is a mock implementation for testing
+type MockDashboardRepository struct {
+	mock.Mock
+}
+
+func (m *MockDashboardRepository) Create(ctx context.Context, dashboard *models.Dashboard) error {
+	args := m.Called(ctx, dashboard)
+	return args.Error(0)
+}
+
+func (m *MockDashboardRepository) Update(ctx context.Context, dashboard *models.Dashboard) error {
+	args := m.Called(ctx, dashboard)
+	return args.Error(0)
+}
+
+func (m *MockDashboardRepository) Delete(ctx context.Context, tenantID, dashboardID string) error {
+	args := m.Called(ctx, tenantID, dashboardID)
+	return args.Error(0)
+}
+
+func (m *MockDashboardRepository) GetByID(ctx context.Context, tenantID, dashboardID string) (*models.Dashboard, error) {
+	args := m.Called(ctx, tenantID, dashboardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Dashboard), args.Error(1)
+}
+
+func (m *MockDashboardRepository) List(ctx context.Context, tenantID string) ([]models.Dashboard, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]models.Dashboard), args.Error(1)
+}
+
+// TestCreateDashboardValidation tests permission and widget checks on save
+func TestCreateDashboardValidation(t *testing.T) {
+	mockDashboardRepo := new(MockDashboardRepository)
+	service := NewSavedDashboardService(mockDashboardRepo, nil)
+
+	dashboard := &models.Dashboard{
+		Title: "Engagement",
+		Widgets: []models.Widget{
+			{WidgetID: "w1", Query: models.DashboardQuery{FilterMode: models.FilterModeHistorical}},
+			{WidgetID: "w1", Query: models.DashboardQuery{FilterMode: "SOMETIMES"}},
+		},
+	}
+
+	err := service.Create(context.Background(), dashboard)
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+
+	ctx := WithPermissions(context.Background(), PermissionManageDashboards)
+	err = service.Create(ctx, dashboard)
+
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Len(t, verr.Errors, 2)
+	mockDashboardRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// TestRunDashboard tests that every widget runs with the overrides and failures stay per widget
+func TestRunDashboard(t *testing.T) {
+	mockDashboardRepo := new(MockDashboardRepository)
+	mockResponseRepo := new(MockResponseRepository)
+	dashboardSvc := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+	service := NewSavedDashboardService(mockDashboardRepo, dashboardSvc)
+
+	ctx := context.Background()
+	mockDashboardRepo.On("GetByID", ctx, "tenant_demo", "dash_1").Return(&models.Dashboard{
+		DashboardID: "dash_1",
+		Widgets: []models.Widget{
+			{WidgetID: "count", Query: models.DashboardQuery{
+				TimeRange:  testTimeRange,
+				FilterMode: models.FilterModeHistorical,
+				Filters:    map[string]interface{}{"department": "Sales", "grade": "A"},
+			}},
+			{WidgetID: "broken", Query: models.DashboardQuery{FilterMode: "SOMETIMES"}},
+		},
+	}, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	overridden := mock.MatchedBy(func(q models.DashboardQuery) bool {
+		return q.TenantID == "tenant_demo" && q.Filters["grade"] == "B" && q.Filters["department"] == "Sales" &&
+			q.TimeRange.From.Equal(from)
+	})
+	mockResponseRepo.On("Query", ctx, overridden).Return([]models.Response{{ResponseID: "resp_1"}}, nil)
+
+	result, err := service.Run(ctx, "tenant_demo", "dash_1", models.DashboardRunRequest{
+		Filters:   map[string]interface{}{"grade": "B"},
+		TimeRange: &models.TimeRange{From: from, To: from.AddDate(0, 3, 0)},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Widgets[0].Result.Count)
+	assert.Nil(t, result.Widgets[1].Result)
+	assert.Contains(t, result.Widgets[1].Error, "invalid filter mode")
+	mockResponseRepo.AssertExpectations(t)
+}
+
+// TestRunDashboardCurrentWidgets tests that CURRENT widgets share the org mapper safely
+// when run concurrently; run with -race
+func TestRunDashboardCurrentWidgets(t *testing.T) {
+	mockDashboardRepo := new(MockDashboardRepository)
+	mockResponseRepo := new(MockResponseRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	dashboardSvc := NewDashboardService(mockResponseRepo, mockOrgRepo, new(MockSurveyRepository))
+	service := NewSavedDashboardService(mockDashboardRepo, dashboardSvc)
+
+	// Identified callers skip redaction, which would write to the shared mock rows
+	ctx := WithPermissions(context.Background(), PermissionViewIdentifiers)
+	var widgets []models.Widget
+	for i, dept := range []string{"Sales", "Sales", "Legal", "Sales", "Legal", "Engineering"} {
+		widgets = append(widgets, models.Widget{WidgetID: fmt.Sprintf("w%d", i), Query: models.DashboardQuery{
+			TimeRange:  testTimeRange,
+			FilterMode: models.FilterModeCurrent,
+			Filters:    map[string]interface{}{"department": dept},
+		}})
+	}
+	mockDashboardRepo.On("GetByID", ctx, "tenant_demo", "dash_1").Return(&models.Dashboard{DashboardID: "dash_1", Widgets: widgets}, nil)
+
+	for _, dept := range []string{"Sales", "Legal", "Engineering"} {
+		unitID := "unit_" + dept
+		mockOrgRepo.On("FindCurrentUnitsByName", ctx, dept).Return([]models.OrgUnit{{UnitID: unitID, UnitName: dept}}, nil)
+		mockOrgRepo.On("FindMappingsByTarget", ctx, unitID).Return([]models.OrgUnitMapping(nil), nil)
+		mockOrgRepo.On("GetMapping", ctx, unitID).Return(nil, nil)
+	}
+	mockResponseRepo.On("Query", ctx, mock.Anything).Return([]models.Response{{ResponseID: "resp_1"}}, nil)
+
+	result, err := service.Run(ctx, "tenant_demo", "dash_1", models.DashboardRunRequest{})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Widgets, len(widgets))
+	for _, w := range result.Widgets {
+		assert.Empty(t, w.Error)
+		assert.Equal(t, 1, w.Result.Count)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// ErasureService handles data-subject erasure requests
+type ErasureService struct {
+	erasureRepo repository.ErasureRepository
+	digestKey   []byte // Server-side secret keying receipt subject digests
+}
+
+func NewErasureService(erasureRepo repository.ErasureRepository, digestKey []byte) *ErasureService {
+	return &ErasureService{erasureRepo: erasureRepo, digestKey: digestKey}
+}
+
+// Erase removes an employee's personal data from the tenant and returns the receipt.
+// Responses survive anonymised so existing aggregates stay stable.
+func (s *ErasureService) Erase(ctx context.Context, tenantID, employeeID string, req models.ErasureRequest) (*models.ErasureReceipt, error) {
+	if !HasPermission(ctx, PermissionEraseEmployees) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionEraseEmployees)
+	}
+	if req.RequestedBy == "" {
+		verr := &ValidationError{}
+		verr.Add("requested_by", "required", "requested_by is required")
+		return nil, verr
+	}
+
+	receipt := &models.ErasureReceipt{
+		ReceiptID:     repository.GenerateID(),
+		TenantID:      tenantID,
+		SubjectDigest: s.SubjectDigest(tenantID, employeeID),
+		RequestedBy:   req.RequestedBy,
+		Reason:        req.Reason,
+	}
+
+	if err := s.erasureRepo.EraseEmployee(ctx, employeeID, receipt); err != nil {
+		return nil, fmt.Errorf("failed to erase employee: %w", err)
+	}
+
+	// The erasure is committed; a failed refresh only leaves the receipt pending
+	if err := s.erasureRepo.RefreshViews(ctx); err != nil {
+		log.Printf("erasure %s: %v", receipt.ReceiptID, err)
+		return receipt, nil
+	}
+	if err := s.erasureRepo.MarkViewsRefreshed(ctx, receipt.ReceiptID); err != nil {
+		log.Printf("erasure %s: %v", receipt.ReceiptID, err)
+		return receipt, nil
+	}
+
+	return s.erasureRepo.GetReceipt(ctx, tenantID, receipt.ReceiptID)
+}
+
+// GetReceipt returns a stored erasure receipt
+func (s *ErasureService) GetReceipt(ctx context.Context, tenantID, receiptID string) (*models.ErasureReceipt, error) {
+	if !HasPermission(ctx, PermissionEraseEmployees) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionEraseEmployees)
+	}
+	return s.erasureRepo.GetReceipt(ctx, tenantID, receiptID)
+}
+
+// SubjectDigest identifies an erased subject without storing their ID. It is keyed with
+// a server-side secret: employee IDs are guessable, so a plain hash could be reversed by
+// hashing candidate IDs.
+func (s *ErasureService) SubjectDigest(tenantID, employeeID string) string {
+	mac := hmac.New(sha256.New, s.digestKey)
+	mac.Write([]byte(tenantID + ":" + employeeID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
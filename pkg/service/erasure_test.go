@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockErasureRepository is a mock implementation for testing
+type MockErasureRepository struct {
+	mock.Mock
+}
+
+func (m *MockErasureRepository) EraseEmployee(ctx context.Context, employeeID string, receipt *models.ErasureReceipt) error {
+	args := m.Called(ctx, employeeID, receipt)
+	return args.Error(0)
+}
+
+func (m *MockErasureRepository) MarkViewsRefreshed(ctx context.Context, receiptID string) error {
+	args := m.Called(ctx, receiptID)
+	return args.Error(0)
+}
+
+func (m *MockErasureRepository) GetReceipt(ctx context.Context, tenantID, receiptID string) (*models.ErasureReceipt, error) {
+	args := m.Called(ctx, tenantID, receiptID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ErasureReceipt), args.Error(1)
+}
+
+func (m *MockErasureRepository) RefreshViews(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// TestEraseRequiresPermission tests that erasure is refused without the erase permission
+// or without naming who requested it
+func TestEraseRequiresPermission(t *testing.T) {
+	mockErasureRepo := new(MockErasureRepository)
+	service := NewErasureService(mockErasureRepo, []byte("digest-key"))
+
+	_, err := service.Erase(context.Background(), "tenant_demo", "emp_123", models.ErasureRequest{RequestedBy: "dpo"})
+
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+
+	ctx := WithPermissions(context.Background(), PermissionEraseEmployees)
+	_, err = service.Erase(ctx, "tenant_demo", "emp_123", models.ErasureRequest{})
+	var verr *ValidationError
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, "requested_by", verr.Errors[0].Field)
+
+	mockErasureRepo.AssertNotCalled(t, "EraseEmployee", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestErase tests the erasure flow: erase, refresh views, stamp the receipt
+func TestErase(t *testing.T) {
+	mockErasureRepo := new(MockErasureRepository)
+	service := NewErasureService(mockErasureRepo, []byte("digest-key"))
+	ctx := WithPermissions(context.Background(), PermissionEraseEmployees)
+
+	var receiptID string
+	mockErasureRepo.On("EraseEmployee", ctx, "emp_123", mock.AnythingOfType("*models.ErasureReceipt")).
+		Run(func(args mock.Arguments) {
+			receipt := args.Get(2).(*models.ErasureReceipt)
+			receiptID = receipt.ReceiptID
+			assert.Equal(t, service.SubjectDigest("tenant_demo", "emp_123"), receipt.SubjectDigest)
+			assert.NotContains(t, receipt.SubjectDigest, "emp_123")
+			plain := sha256.Sum256([]byte("tenant_demo:emp_123"))
+			assert.NotEqual(t, hex.EncodeToString(plain[:]), receipt.SubjectDigest)
+			other := NewErasureService(mockErasureRepo, []byte("other-key"))
+			assert.NotEqual(t, other.SubjectDigest("tenant_demo", "emp_123"), receipt.SubjectDigest)
+		}).Return(nil)
+	mockErasureRepo.On("RefreshViews", ctx).Return(nil)
+	mockErasureRepo.On("MarkViewsRefreshed", ctx, mock.Anything).Return(nil)
+	mockErasureRepo.On("GetReceipt", ctx, "tenant_demo", mock.Anything).
+		Return(&models.ErasureReceipt{ReceiptID: "stored"}, nil)
+
+	receipt, err := service.Erase(ctx, "tenant_demo", "emp_123", models.ErasureRequest{RequestedBy: "dpo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "stored", receipt.ReceiptID)
+	mockErasureRepo.AssertCalled(t, "MarkViewsRefreshed", ctx, receiptID)
+}
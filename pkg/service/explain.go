@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// PermissionExplainQueries allows explaining dashboard queries, which reveals the
+// generated SQL, the org mapping and Postgres plans
+const PermissionExplainQueries Permission = "dashboards:explain"
+
+// explain resolves a query the way Query would and plans its SQL without running it.
+// Only the lookups needed for resolution are executed: org mappings and, when grouping
+// by org unit, the distinct unit IDs in the filtered set.
+func (s *DashboardService) explain(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
+	if !HasPermission(ctx, PermissionExplainQueries) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionExplainQueries)
+	}
+	if err := s.loadArchived(ctx, &query); err != nil {
+		return nil, err
+	}
+
+	explanation := &models.QueryExplanation{FilterMode: query.FilterMode}
+
+	switch query.FilterMode {
+	case models.FilterModeHistorical:
+		pass, err := s.explainPass(ctx, query, models.FilterModeHistorical)
+		if err != nil {
+			return nil, err
+		}
+		explanation.Passes = append(explanation.Passes, *pass)
+	case models.FilterModeCurrent:
+		if err := s.explainCurrent(ctx, query, explanation); err != nil {
+			return nil, err
+		}
+	case models.FilterModeHybrid:
+		pass, err := s.explainPass(ctx, query, models.FilterModeHistorical)
+		if err != nil {
+			return nil, err
+		}
+		explanation.Passes = append(explanation.Passes, *pass)
+		if err := s.explainCurrent(ctx, query, explanation); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid filter mode: %s", ErrInvalidRequest, query.FilterMode)
+	}
+
+	return &models.DashboardResult{Explain: explanation}, nil
+}
+
+// explainCurrent mirrors queryCurrent, recording how the department filter was mapped.
+// The chains are the ones the backward traversal walked to build the unit filter.
+func (s *DashboardService) explainCurrent(ctx context.Context, query models.DashboardQuery, explanation *models.QueryExplanation) error {
+	dept, hasDept := query.Filters["department"].(string)
+	if err := s.mapCurrentFilters(ctx, &query); err != nil {
+		return err
+	}
+
+	if hasDept {
+		chains, err := s.orgMapper.TraceCurrentToHistorical(ctx, query.TenantID, dept)
+		if err != nil {
+			return fmt.Errorf("failed to map department %s: %w", dept, err)
+		}
+		unitIDs, _ := query.Filters["unit_id"].([]string)
+		mapping := &models.DepartmentMapping{
+			Department:        dept,
+			HistoricalUnitIDs: unitIDs,
+			Chains:            append([]models.MappingChain{}, chains...),
+		}
+		for _, chain := range chains {
+			if len(chain.Steps) > 0 {
+				mapping.MappingApplied = true
+			}
+		}
+		switch {
+		case len(chains) == 0:
+			mapping.Note = "no mapping applied: no current unit has this name, so the filter selects nothing"
+		case !mapping.MappingApplied:
+			mapping.Note = "no mapping applied: the current units were not restructured"
+		}
+		explanation.DepartmentMapping = mapping
+	}
+
+	if groupsByOrg(query.GroupBy) {
+		unitMapping, err := s.currentUnitMapping(ctx, query)
+		if err != nil {
+			return err
+		}
+		query.UnitMapping = unitMapping
+	}
+
+	pass, err := s.explainPass(ctx, query, models.FilterModeCurrent)
+	if err != nil {
+		return err
+	}
+	explanation.Passes = append(explanation.Passes, *pass)
+	return nil
+}
+
+// explainPass mirrors execute for an already translated query
+func (s *DashboardService) explainPass(ctx context.Context, query models.DashboardQuery, mode models.FilterMode) (*models.ExplainedPass, error) {
+	path, reason := planQuery(query)
+	if path != models.QueryPathMaterializedView && query.UnitMapping == nil &&
+		containsBenchmark(query.Benchmarks, models.BenchmarkParentUnit) {
+		unitMapping, err := s.currentUnitMapping(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		query.UnitMapping = unitMapping
+	}
+
+	statements, err := s.responseRepo.Explain(ctx, query, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ExplainedPass{
+		Mode:        mode,
+		Filters:     query.Filters,
+		UnitMapping: query.UnitMapping,
+		Path:        path,
+		Reason:      reason,
+		Statements:  statements,
+	}, nil
+}
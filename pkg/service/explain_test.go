@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// TestExplainCurrentQuery tests that explain traces the department mapping and plans
+// the translated query without running it
+func TestExplainCurrentQuery(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(mockResponseRepo, mockOrgRepo, new(MockSurveyRepository))
+
+	ctx := WithPermissions(context.Background(), PermissionExplainQueries)
+	query := models.DashboardQuery{
+		TimeRange:  testTimeRange,
+		FilterMode: models.FilterModeCurrent,
+		Filters:    map[string]interface{}{"department": "Revenue", "location": "SG"},
+		GroupBy:    []string{"department"},
+		Explain:    true,
+	}
+
+	merge := models.OrgUnitMapping{SourceUnitID: "unit_sales", RelationshipType: models.MappingTypeMerge, TargetUnitIDs: []string{"unit_revenue"}}
+	mockOrgRepo.On("FindCurrentUnitsByName", ctx, "Revenue").Return([]models.OrgUnit{{UnitID: "unit_revenue", UnitName: "Revenue"}}, nil)
+	mockOrgRepo.On("FindMappingsByTarget", ctx, "unit_revenue").Return([]models.OrgUnitMapping{merge}, nil)
+	mockOrgRepo.On("FindMappingsByTarget", ctx, "unit_sales").Return([]models.OrgUnitMapping(nil), nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_sales").Return(&merge, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_revenue").Return(nil, nil)
+	mockResponseRepo.On("DistinctValues", ctx, mock.Anything, "unit_id").Return([]string{"unit_sales"}, nil)
+
+	statements := []models.ExplainedStatement{{Purpose: "responses", EstimatedRows: 42}}
+	translated := mock.MatchedBy(func(q models.DashboardQuery) bool {
+		return q.Filters["department"] == nil && q.UnitMapping["unit_sales"] == "unit_revenue"
+	})
+	mockResponseRepo.On("Explain", ctx, translated, models.QueryPathBaseTable).Return(statements, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result.Responses)
+	explanation := result.Explain
+	assert.Equal(t, models.FilterModeCurrent, explanation.FilterMode)
+	assert.Equal(t, []string{"unit_revenue", "unit_sales"}, explanation.DepartmentMapping.HistoricalUnitIDs)
+	assert.True(t, explanation.DepartmentMapping.MappingApplied)
+	assert.Empty(t, explanation.DepartmentMapping.Note)
+	assert.Empty(t, explanation.DepartmentMapping.Chains[0].Steps)
+	chain := explanation.DepartmentMapping.Chains[1]
+	assert.Equal(t, []models.OrgUnitMapping{merge}, chain.Steps)
+	assert.Equal(t, "unit_revenue", chain.CurrentUnitID)
+	assert.True(t, chain.Attributable)
+
+	assert.Len(t, explanation.Passes, 1)
+	pass := explanation.Passes[0]
+	assert.Equal(t, []string{"unit_revenue", "unit_sales"}, pass.Filters["unit_id"])
+	assert.Equal(t, "SG", pass.Filters["location"])
+	assert.Equal(t, statements, pass.Statements)
+	mockResponseRepo.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+	mockResponseRepo.AssertNotCalled(t, "Aggregate", mock.Anything, mock.Anything)
+}
+
+// TestExplainUnknownDepartment tests that explain says so when no mapping applied
+func TestExplainUnknownDepartment(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(mockResponseRepo, mockOrgRepo, new(MockSurveyRepository))
+
+	ctx := WithPermissions(context.Background(), PermissionExplainQueries)
+	query := summaryQuery()
+	query.FilterMode = models.FilterModeCurrent
+	query.Explain = true
+
+	mockOrgRepo.On("FindCurrentUnitsByName", ctx, "Sales").Return([]models.OrgUnit(nil), nil)
+	mockResponseRepo.On("Explain", ctx, mock.Anything, mock.Anything).Return([]models.ExplainedStatement{{Purpose: "responses"}}, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	mapping := result.Explain.DepartmentMapping
+	assert.Empty(t, mapping.Chains)
+	assert.Empty(t, mapping.HistoricalUnitIDs)
+	assert.False(t, mapping.MappingApplied)
+	assert.Equal(t, "no mapping applied: no current unit has this name, so the filter selects nothing", mapping.Note)
+}
+
+// TestExplainHybridAndPermission tests that HYBRID explains both passes and that
+// explaining requires its own permission
+func TestExplainHybridAndPermission(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(mockResponseRepo, mockOrgRepo, new(MockSurveyRepository))
+
+	query := summaryQuery()
+	query.FilterMode = models.FilterModeHybrid
+	query.Explain = true
+
+	_, err := service.Query(context.Background(), query)
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+
+	ctx := WithPermissions(context.Background(), PermissionExplainQueries)
+	mockOrgRepo.On("FindCurrentUnitsByName", ctx, "Sales").Return([]models.OrgUnit{{UnitID: "unit_sales", UnitName: "Sales"}}, nil)
+	mockOrgRepo.On("FindMappingsByTarget", ctx, "unit_sales").Return([]models.OrgUnitMapping(nil), nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_sales").Return(nil, nil)
+	mockResponseRepo.On("Explain", ctx, mock.Anything, models.QueryPathBaseTable).
+		Return([]models.ExplainedStatement{{Purpose: "responses"}, {Purpose: "aggregate"}}, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "unit_sales", result.Explain.DepartmentMapping.Chains[0].CurrentUnitID)
+	assert.False(t, result.Explain.DepartmentMapping.MappingApplied)
+	assert.Equal(t, "no mapping applied: the current units were not restructured", result.Explain.DepartmentMapping.Note)
+	assert.Len(t, result.Explain.Passes, 2)
+	assert.Equal(t, models.FilterModeHistorical, result.Explain.Passes[0].Mode)
+	assert.Equal(t, "view holds historical snapshot attributes only", result.Explain.Passes[0].Reason)
+	assert.Equal(t, models.FilterModeCurrent, result.Explain.Passes[1].Mode)
+	assert.Equal(t, []string{"unit_sales"}, result.Explain.Passes[1].Filters["unit_id"])
+	assert.Len(t, result.Explain.Passes[1].Statements, 2)
+}
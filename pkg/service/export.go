@@ -0,0 +1,444 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+const (
+	// MaxSyncExportRows is the largest response export served within the request;
+	// larger ones must run as a job
+	MaxSyncExportRows = 10000
+	// MaxExportRows caps every export, below the XLSX limit of 1,048,576 rows per sheet
+	MaxExportRows = 1000000
+	// ExportChunkSize is how much of a job's file is held in memory before it is stored
+	ExportChunkSize = 1 << 20
+)
+
+// ExportRetention is how long a job's file can be downloaded after it finished
+const ExportRetention = 24 * time.Hour
+
+// PermissionExportResponses allows exporting individual responses rather than aggregates
+const PermissionExportResponses Permission = "responses:export"
+
+var (
+	// ErrExportTooLarge is returned when a synchronous export selects too many responses
+	ErrExportTooLarge = errors.New("export too large")
+	// ErrExportSuppressed is returned when too few responses are selected to release them
+	ErrExportSuppressed = errors.New("export suppressed below the anonymity threshold")
+)
+
+// quasiIdentifierKeys are exact snapshot values that, combined, can single out an
+// employee; exports keep the banded versions instead
+var quasiIdentifierKeys = []string{"age", "tenure"}
+
+// ExportService exports dashboard results as CSV or XLSX, directly or as a job
+type ExportService struct {
+	dashboards *DashboardService
+	jobRepo    repository.ExportJobRepository
+}
+
+func NewExportService(dashboards *DashboardService, jobRepo repository.ExportJobRepository) *ExportService {
+	return &ExportService{dashboards: dashboards, jobRepo: jobRepo}
+}
+
+// Export writes the export to w and returns the number of data rows. Every check runs
+// before the first byte is written, so a failed export never leaves a partial file
+// unless the database fails mid-stream.
+func (s *ExportService) Export(ctx context.Context, req models.ExportRequest, w io.Writer) (int, error) {
+	if err := s.authorize(ctx, req); err != nil {
+		return 0, err
+	}
+	return s.write(ctx, req, w, MaxSyncExportRows)
+}
+
+// Start runs the export as a job and returns it while it is still running. Expired
+// jobs of the tenant are purged first.
+func (s *ExportService) Start(ctx context.Context, req models.ExportRequest) (*models.ExportJob, error) {
+	if err := s.authorize(ctx, req); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.jobRepo.DeleteExpired(ctx, req.Query.TenantID); err != nil {
+		log.Printf("export: %v", err)
+	}
+
+	job := &models.ExportJob{
+		Format:     req.Format,
+		Kind:       req.Kind,
+		Request:    req,
+		Identified: req.Kind == models.ExportKindResponses && HasPermission(ctx, PermissionViewIdentifiers),
+		TenantID:   req.Query.TenantID,
+	}
+	if err := s.jobRepo.Create(ctx, job, ExportRetention); err != nil {
+		return nil, err
+	}
+
+	// The job outlives the request but keeps the caller's permissions
+	go s.run(context.WithoutCancel(ctx), job)
+	return job, nil
+}
+
+// Job returns an export job, or nil if it does not exist or expired. The caller needs
+// the permissions the export was started with.
+func (s *ExportService) Job(ctx context.Context, tenantID, jobID string) (*models.ExportJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, tenantID, jobID)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, job.Request); err != nil {
+		return nil, err
+	}
+	if job.Identified && !HasPermission(ctx, PermissionViewIdentifiers) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionViewIdentifiers)
+	}
+	return job, nil
+}
+
+// Download streams the file of a succeeded job to w. Permissions are checked again,
+// as they may have been revoked since the export was started.
+func (s *ExportService) Download(ctx context.Context, tenantID, jobID string, w io.Writer) error {
+	job, err := s.Job(ctx, tenantID, jobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("export %w: %s", repository.ErrNotFound, jobID)
+	}
+	if job.Status != models.ExportJobSucceeded {
+		return fmt.Errorf("%w: export is %s", repository.ErrConflict, job.Status)
+	}
+	return s.jobRepo.Content(ctx, tenantID, jobID, w)
+}
+
+func (s *ExportService) run(ctx context.Context, job *models.ExportJob) {
+	out := &chunkWriter{ctx: ctx, repo: s.jobRepo, job: job, buf: make([]byte, 0, ExportChunkSize)}
+	rows, err := s.write(ctx, job.Request, out, MaxExportRows)
+	if err == nil {
+		err = out.flush()
+	}
+	if err != nil {
+		if ferr := s.jobRepo.Fail(ctx, job.TenantID, job.JobID, err.Error()); ferr != nil {
+			log.Printf("export %s: %v (while recording: %v)", job.JobID, ferr, err)
+		}
+		return
+	}
+	if err := s.jobRepo.Complete(ctx, job.TenantID, job.JobID, rows, ExportRetention); err != nil {
+		log.Printf("export %s: %v", job.JobID, err)
+	}
+}
+
+// chunkWriter streams a job's file to storage, holding at most one chunk in memory
+type chunkWriter struct {
+	ctx  context.Context
+	repo repository.ExportJobRepository
+	job  *models.ExportJob
+	buf  []byte
+	seq  int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		k := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+k]
+		p = p[k:]
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush stores the buffered bytes as the next chunk
+func (w *chunkWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if err := w.repo.WriteChunk(w.ctx, w.job.TenantID, w.job.JobID, w.seq, w.buf); err != nil {
+		return err
+	}
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// authorize validates the request and checks the caller may export responses
+func (s *ExportService) authorize(ctx context.Context, req models.ExportRequest) error {
+	if err := validateExport(req); err != nil {
+		return err
+	}
+	if req.Kind == models.ExportKindResponses && !HasPermission(ctx, PermissionExportResponses) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionExportResponses)
+	}
+	return nil
+}
+
+func validateExport(req models.ExportRequest) error {
+	verr := &ValidationError{}
+	switch req.Format {
+	case models.ExportFormatCSV, models.ExportFormatXLSX:
+	default:
+		verr.Add("format", "invalid", "format must be csv or xlsx")
+	}
+
+	switch req.Kind {
+	case models.ExportKindGroups:
+		if len(req.Query.GroupBy) == 0 && len(req.Query.Metrics) == 0 {
+			verr.Add("query.group_by", "required", "a groups export needs group_by or metrics")
+		}
+	case models.ExportKindResponses:
+		if req.Query.SurveyID == "" {
+			verr.Add("query.survey_id", "required", "a responses export needs survey_id for its columns")
+		}
+	default:
+		verr.Add("kind", "invalid", "kind must be groups or responses")
+	}
+	return verr.OrNil()
+}
+
+func (s *ExportService) write(ctx context.Context, req models.ExportRequest, w io.Writer, limit int) (int, error) {
+	if req.Kind == models.ExportKindResponses {
+		return s.writeResponses(ctx, req, w, limit)
+	}
+	return s.writeGroups(ctx, req, w)
+}
+
+// writeGroups exports one row per group of the dashboard query, with the same
+// suppression as the dashboard itself
+func (s *ExportService) writeGroups(ctx context.Context, req models.ExportRequest, w io.Writer) (int, error) {
+	result, err := s.dashboards.Query(ctx, req.Query)
+	if err != nil {
+		return 0, err
+	}
+	labels := s.questionLabels(ctx, req.Query)
+
+	header := make([]interface{}, 0, len(req.Query.GroupBy)+1+2*len(req.Query.Metrics))
+	for _, field := range req.Query.GroupBy {
+		header = append(header, fieldLabel(field, labels))
+	}
+	header = append(header, "Responses")
+	for _, m := range req.Query.Metrics {
+		label := fmt.Sprintf("%s (%s)", labelOr(labels, m.QuestionID), m.Type)
+		header = append(header, label, label+" respondents")
+	}
+
+	tw, err := newTableWriter(req.Format, w)
+	if err != nil {
+		return 0, err
+	}
+	if err := tw.WriteRow(header); err != nil {
+		return 0, err
+	}
+
+	for _, g := range result.Groups {
+		row := make([]interface{}, 0, len(header))
+		for _, field := range req.Query.GroupBy {
+			row = append(row, g.Key[field])
+		}
+		var count interface{} = g.Count
+		if g.Suppressed {
+			count = nil
+		}
+		row = append(row, count)
+		for _, m := range g.Metrics {
+			var value, respondents interface{} = nil, m.Respondents
+			if m.Value != nil {
+				value = *m.Value
+			}
+			if m.Suppressed {
+				respondents = nil
+			}
+			row = append(row, value, respondents)
+		}
+		if err := tw.WriteRow(row); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(result.Groups), tw.Close()
+}
+
+// writeResponses exports one row per response, flattened into snapshot and answer
+// columns. Responses are only released when the selection reaches the anonymity
+// threshold; identifiers, exact ages and tenures, free text and the raw answers of
+// surveys without question definitions need PermissionViewIdentifiers.
+func (s *ExportService) writeResponses(ctx context.Context, req models.ExportRequest, w io.Writer, limit int) (int, error) {
+	d := s.dashboards
+	query, err := d.rowQuery(ctx, req.Query)
+	if err != nil {
+		return 0, err
+	}
+	total, err := d.countResponses(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	// The count itself is below the threshold, so it is not reported
+	if total < d.anonymityThreshold || total == 0 {
+		return 0, ErrExportSuppressed
+	}
+	if total > limit {
+		return 0, fmt.Errorf("%w: %d responses selected, limit %d", ErrExportTooLarge, total, limit)
+	}
+
+	survey, err := d.surveyRepo.GetByID(ctx, query.TenantID, query.SurveyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get survey: %w", err)
+	}
+	if survey == nil {
+		return 0, fmt.Errorf("survey %w: %s", repository.ErrNotFound, query.SurveyID)
+	}
+	keys, err := d.responseRepo.SnapshotKeys(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	identified := HasPermission(ctx, PermissionViewIdentifiers)
+	keys = exportSnapshotKeys(keys, identified)
+	var questions []models.Question
+	for _, q := range survey.Questions {
+		if q.Type != models.QuestionTypeFreeText || identified {
+			questions = append(questions, q)
+		}
+	}
+
+	header := []interface{}{"Response ID", "Survey ID", "Submitted at", "Status"}
+	if identified {
+		header = append(header, "Employee ID")
+	}
+	for _, key := range keys {
+		header = append(header, key)
+	}
+	for _, q := range questions {
+		label := q.Text
+		if label == "" {
+			label = q.QuestionID
+		}
+		header = append(header, label)
+	}
+	// Legacy surveys have no definition, so their raw answers may hold free text
+	legacyAnswers := len(survey.Questions) == 0 && identified
+	if legacyAnswers {
+		header = append(header, "Answers")
+	}
+
+	tw, err := newTableWriter(req.Format, w)
+	if err != nil {
+		return 0, err
+	}
+	if err := tw.WriteRow(header); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	err = d.eachResponse(ctx, query, func(r *models.Response) error {
+		row := []interface{}{r.ResponseID, r.SurveyID, r.SubmittedAt.UTC().Format(time.RFC3339), string(r.Status)}
+		if identified {
+			row = append(row, r.EmployeeID)
+		}
+		for _, key := range keys {
+			row = append(row, exportValue(r.SnapshotCore[key]))
+		}
+
+		var answers map[string]interface{}
+		if len(r.Answers) > 0 {
+			if err := json.Unmarshal(r.Answers, &answers); err != nil {
+				return fmt.Errorf("failed to decode answers of %s: %w", r.ResponseID, err)
+			}
+		}
+		for _, q := range questions {
+			row = append(row, exportValue(answers[q.QuestionID]))
+		}
+		if legacyAnswers {
+			row = append(row, string(r.Answers))
+		}
+
+		rows++
+		return tw.WriteRow(row)
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, tw.Close()
+}
+
+// questionLabels maps question IDs to their text when the query names a survey
+func (s *ExportService) questionLabels(ctx context.Context, query models.DashboardQuery) map[string]string {
+	labels := make(map[string]string)
+	if query.SurveyID == "" {
+		return labels
+	}
+	survey, err := s.dashboards.surveyRepo.GetByID(ctx, query.TenantID, query.SurveyID)
+	if err != nil || survey == nil {
+		return labels // Fall back to question IDs
+	}
+	for _, q := range survey.Questions {
+		labels[q.QuestionID] = q.Text
+	}
+	return labels
+}
+
+// fieldLabel labels a group-by field; answer fields use the question text
+func fieldLabel(field string, labels map[string]string) string {
+	if questionID, ok := strings.CutPrefix(field, models.AnswerFieldPrefix); ok {
+		return labelOr(labels, questionID)
+	}
+	return field
+}
+
+func labelOr(labels map[string]string, questionID string) string {
+	if text := labels[questionID]; text != "" {
+		return text
+	}
+	return questionID
+}
+
+// exportSnapshotKeys drops identifying snapshot keys unless the caller may see them
+func exportSnapshotKeys(keys []string, identified bool) []string {
+	hidden := make(map[string]bool)
+	if !identified {
+		for _, key := range append(directIdentifierKeys, quasiIdentifierKeys...) {
+			hidden[key] = true
+		}
+	}
+
+	kept := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !hidden[key] {
+			kept = append(kept, key)
+		}
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+// exportValue flattens a JSON value into a cell; multi-choice answers are joined
+func exportValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, string, float64:
+		return v
+	case bool:
+		return fmt.Sprint(v)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, "; ")
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportJobRepository is a mock implementation for testing
+type MockExportJobRepository struct {
+	mock.Mock
+}
+
+func (m *MockExportJobRepository) Create(ctx context.Context, job *models.ExportJob, retention time.Duration) error {
+	args := m.Called(ctx, job, retention)
+	job.JobID = "job_1"
+	job.Status = models.ExportJobRunning
+	return args.Error(0)
+}
+
+func (m *MockExportJobRepository) GetByID(ctx context.Context, tenantID, jobID string) (*models.ExportJob, error) {
+	args := m.Called(ctx, tenantID, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExportJob), args.Error(1)
+}
+
+func (m *MockExportJobRepository) WriteChunk(ctx context.Context, tenantID, jobID string, seq int, data []byte) error {
+	// The writer reuses its buffer, so keep a copy for assertions
+	args := m.Called(ctx, tenantID, jobID, seq, append([]byte(nil), data...))
+	return args.Error(0)
+}
+
+func (m *MockExportJobRepository) Content(ctx context.Context, tenantID, jobID string, w io.Writer) error {
+	args := m.Called(ctx, tenantID, jobID, w)
+	if content, ok := args.Get(0).([]byte); ok {
+		w.Write(content)
+	}
+	return args.Error(1)
+}
+
+func (m *MockExportJobRepository) Complete(ctx context.Context, tenantID, jobID string, rows int, retention time.Duration) error {
+	args := m.Called(ctx, tenantID, jobID, rows, retention)
+	return args.Error(0)
+}
+
+func (m *MockExportJobRepository) Fail(ctx context.Context, tenantID, jobID, reason string) error {
+	args := m.Called(ctx, tenantID, jobID, reason)
+	return args.Error(0)
+}
+
+func (m *MockExportJobRepository) DeleteExpired(ctx context.Context, tenantID string) (int, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Int(0), args.Error(1)
+}
+
+func exportSurvey() *models.Survey {
+	return &models.Survey{
+		SurveyID: "s1",
+		Questions: []models.Question{
+			{QuestionID: "q1", Text: "I feel valued at work", Type: models.QuestionTypeLikert},
+			{QuestionID: "q2", Text: "Which benefits do you use?", Type: models.QuestionTypeMultiChoice, Options: []string{"gym", "lunch"}},
+			{QuestionID: "q3", Text: "Anything else?", Type: models.QuestionTypeFreeText},
+		},
+	}
+}
+
+func exportResponsesRequest(format models.ExportFormat) models.ExportRequest {
+	return models.ExportRequest{
+		Format: format,
+		Kind:   models.ExportKindResponses,
+		Query:  models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical, TenantID: "t1", SurveyID: "s1"},
+	}
+}
+
+// TestExportResponsesCSV tests the flattened, anonymity-safe response export
+func TestExportResponsesCSV(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockSurveyRepo := new(MockSurveyRepository)
+	dashboards := NewDashboardService(mockResponseRepo, new(MockOrgRepository), mockSurveyRepo)
+	exports := NewExportService(dashboards, new(MockExportJobRepository))
+
+	ctx := WithPermissions(context.Background(), PermissionExportResponses)
+	mockResponseRepo.On("Aggregate", ctx, mock.Anything).Return([]models.GroupResult{{Count: 8}}, nil)
+	mockSurveyRepo.On("GetByID", ctx, "t1", "s1").Return(exportSurvey(), nil)
+	mockResponseRepo.On("SnapshotKeys", ctx, mock.Anything).
+		Return([]string{"age", "age_band", "department", "employee_email"}, nil)
+	mockResponseRepo.On("Iterate", ctx, mock.Anything).Return([]models.Response{{
+		ResponseID:   "r1",
+		SurveyID:     "s1",
+		EmployeeID:   "emp_1",
+		SubmittedAt:  time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		Status:       models.ResponseStatusActive,
+		SnapshotCore: map[string]interface{}{"age": 31.0, "age_band": "25-34", "department": "=HYPERLINK()"},
+		Answers:      json.RawMessage(`{"q1": 4, "q2": ["gym", "lunch"], "q3": "My manager is great"}`),
+	}}, nil)
+
+	var buf bytes.Buffer
+	rows, err := exports.Export(ctx, exportResponsesRequest(models.ExportFormatCSV), &buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rows)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// Exact age, email, employee ID and free text need PermissionViewIdentifiers
+	assert.Equal(t, "Response ID,Survey ID,Submitted at,Status,age_band,department,I feel valued at work,Which benefits do you use?", lines[0])
+	assert.Equal(t, "r1,s1,2024-03-01T09:00:00Z,ACTIVE,25-34,'=HYPERLINK(),4,gym; lunch", lines[1])
+}
+
+// TestExportLegacySurveyAnswers tests that raw answers of a survey without question
+// definitions are only exported to identified callers, as they may hold free text
+func TestExportLegacySurveyAnswers(t *testing.T) {
+	for _, identified := range []bool{false, true} {
+		mockResponseRepo := new(MockResponseRepository)
+		mockSurveyRepo := new(MockSurveyRepository)
+		dashboards := NewDashboardService(mockResponseRepo, new(MockOrgRepository), mockSurveyRepo)
+		exports := NewExportService(dashboards, new(MockExportJobRepository))
+
+		ctx := WithPermissions(context.Background(), PermissionExportResponses)
+		if identified {
+			ctx = WithPermissions(context.Background(), PermissionExportResponses, PermissionViewIdentifiers)
+		}
+		mockResponseRepo.On("Aggregate", ctx, mock.Anything).Return([]models.GroupResult{{Count: 8}}, nil)
+		mockSurveyRepo.On("GetByID", ctx, "t1", "s1").Return(&models.Survey{SurveyID: "s1"}, nil)
+		mockResponseRepo.On("SnapshotKeys", ctx, mock.Anything).Return([]string{"department"}, nil)
+		mockResponseRepo.On("Iterate", ctx, mock.Anything).Return([]models.Response{{
+			ResponseID:   "r1",
+			SurveyID:     "s1",
+			SnapshotCore: map[string]interface{}{"department": "Sales"},
+			Answers:      json.RawMessage(`{"comment":"My manager is great"}`),
+		}}, nil)
+
+		var buf bytes.Buffer
+		_, err := exports.Export(ctx, exportResponsesRequest(models.ExportFormatCSV), &buf)
+
+		assert.NoError(t, err)
+		assert.Equal(t, identified, strings.Contains(buf.String(), "My manager is great"))
+		assert.Equal(t, identified, strings.Contains(buf.String(), "Answers"))
+	}
+}
+
+// TestExportResponsesChecks tests permission, suppression and size checks before writing
+func TestExportResponsesChecks(t *testing.T) {
+	ctx := WithPermissions(context.Background(), PermissionExportResponses)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		count   int
+		wantErr error
+	}{
+		{"without permission", context.Background(), 50, ErrPermissionDenied},
+		{"below threshold", ctx, 3, ErrExportSuppressed},
+		{"too large to stream", ctx, MaxSyncExportRows + 1, ErrExportTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockResponseRepo := new(MockResponseRepository)
+			dashboards := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+			exports := NewExportService(dashboards, new(MockExportJobRepository))
+			mockResponseRepo.On("Aggregate", tt.ctx, mock.Anything).Return([]models.GroupResult{{Count: tt.count}}, nil)
+
+			var buf bytes.Buffer
+			_, err := exports.Export(tt.ctx, exportResponsesRequest(models.ExportFormatCSV), &buf)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
+// TestExportGroupsXLSX tests that aggregation tables export as a readable workbook
+func TestExportGroupsXLSX(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockSurveyRepo := new(MockSurveyRepository)
+	dashboards := NewDashboardService(mockResponseRepo, new(MockOrgRepository), mockSurveyRepo)
+	exports := NewExportService(dashboards, new(MockExportJobRepository))
+
+	ctx := context.Background()
+	req := models.ExportRequest{
+		Format: models.ExportFormatXLSX,
+		Kind:   models.ExportKindGroups,
+		Query: models.DashboardQuery{
+			TimeRange:  testTimeRange,
+			FilterMode: models.FilterModeHistorical,
+			TenantID:   "t1",
+			SurveyID:   "s1",
+			GroupBy:    []string{"department"},
+			Metrics:    []models.MetricSpec{{Type: models.MetricTypeMean, QuestionID: "q1"}},
+		},
+	}
+
+	mockSurveyRepo.On("GetByID", ctx, "t1", "s1").Return(exportSurvey(), nil)
+	mockResponseRepo.On("Query", ctx, mock.Anything).Return([]models.Response{}, nil)
+	mockResponseRepo.On("Aggregate", ctx, mock.Anything).Return([]models.GroupResult{{
+		Key:     map[string]string{"department": "R&D"},
+		Count:   12,
+		Metrics: []models.MetricValue{{Type: models.MetricTypeMean, QuestionID: "q1", Respondents: 12, Mean: 3.5, StdDev: 1}},
+	}}, nil)
+
+	var buf bytes.Buffer
+	rows, err := exports.Export(ctx, req, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rows)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			b, _ := io.ReadAll(rc)
+			sheet = string(b)
+		}
+	}
+	assert.Contains(t, sheet, "I feel valued at work (MEAN)")
+	assert.Contains(t, sheet, "R&amp;D")
+	assert.Contains(t, sheet, "<c><v>3.5</v></c>")
+}
+
+// TestExportAsyncJob tests that async exports store the finished file on the job
+func TestExportAsyncJob(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockSurveyRepo := new(MockSurveyRepository)
+	mockJobRepo := new(MockExportJobRepository)
+	dashboards := NewDashboardService(mockResponseRepo, new(MockOrgRepository), mockSurveyRepo)
+	exports := NewExportService(dashboards, mockJobRepo)
+
+	ctx := WithPermissions(context.Background(), PermissionExportResponses)
+	req := exportResponsesRequest(models.ExportFormatCSV)
+	req.Async = true
+
+	mockResponseRepo.On("Aggregate", mock.Anything, mock.Anything).Return([]models.GroupResult{{Count: MaxSyncExportRows + 1}}, nil)
+	mockSurveyRepo.On("GetByID", mock.Anything, "t1", "s1").Return(exportSurvey(), nil)
+	mockResponseRepo.On("SnapshotKeys", mock.Anything, mock.Anything).Return([]string{"department"}, nil)
+	mockResponseRepo.On("Iterate", mock.Anything, mock.Anything).Return([]models.Response{
+		{ResponseID: "r1", SnapshotCore: map[string]interface{}{"department": "Sales"}},
+	}, nil)
+
+	var content bytes.Buffer
+	done := make(chan struct{})
+	mockJobRepo.On("DeleteExpired", ctx, "t1").Return(0, nil)
+	mockJobRepo.On("Create", ctx, mock.MatchedBy(func(job *models.ExportJob) bool { return !job.Identified }), ExportRetention).Return(nil)
+	mockJobRepo.On("WriteChunk", mock.Anything, "t1", "job_1", mock.Anything, mock.Anything).Return(nil).
+		Run(func(args mock.Arguments) { content.Write(args.Get(4).([]byte)) })
+	mockJobRepo.On("Complete", mock.Anything, "t1", "job_1", 1, ExportRetention).Return(nil).
+		Run(func(args mock.Arguments) { close(done) })
+
+	job, err := exports.Start(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ExportJobRunning, job.Status)
+
+	select {
+	case <-done:
+		assert.Contains(t, content.String(), "Sales")
+	case <-time.After(time.Second):
+		t.Fatal("export job did not complete")
+	}
+}
+
+// TestExportChunkWriter tests that a job's file is stored in full chunks as it is written
+func TestExportChunkWriter(t *testing.T) {
+	mockJobRepo := new(MockExportJobRepository)
+	job := &models.ExportJob{JobID: "job_1", TenantID: "t1"}
+	out := &chunkWriter{ctx: context.Background(), repo: mockJobRepo, job: job, buf: make([]byte, 0, 4)}
+
+	mockJobRepo.On("WriteChunk", mock.Anything, "t1", "job_1", 0, []byte("abcd")).Return(nil).Once()
+	mockJobRepo.On("WriteChunk", mock.Anything, "t1", "job_1", 1, []byte("efgh")).Return(nil).Once()
+	mockJobRepo.On("WriteChunk", mock.Anything, "t1", "job_1", 2, []byte("ij")).Return(nil).Once()
+
+	n, err := out.Write([]byte("abcdef"))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+	_, err = out.Write([]byte("ghij"))
+	assert.NoError(t, err)
+	assert.NoError(t, out.flush())
+	assert.NoError(t, out.flush())
+
+	mockJobRepo.AssertExpectations(t)
+}
+
+// TestExportDownloadChecks tests that downloads re-check the caller's permissions
+func TestExportDownloadChecks(t *testing.T) {
+	dashboards := NewDashboardService(new(MockResponseRepository), new(MockOrgRepository), new(MockSurveyRepository))
+	mockJobRepo := new(MockExportJobRepository)
+	exports := NewExportService(dashboards, mockJobRepo)
+
+	identified := &models.ExportJob{JobID: "job_1", Status: models.ExportJobSucceeded, Identified: true,
+		Request: exportResponsesRequest(models.ExportFormatCSV)}
+	running := &models.ExportJob{JobID: "job_2", Status: models.ExportJobRunning,
+		Request: exportResponsesRequest(models.ExportFormatCSV)}
+	mockJobRepo.On("GetByID", mock.Anything, "t1", "job_1").Return(identified, nil)
+	mockJobRepo.On("GetByID", mock.Anything, "t1", "job_2").Return(running, nil)
+	mockJobRepo.On("GetByID", mock.Anything, "t1", "expired").Return(nil, nil)
+	mockJobRepo.On("Content", mock.Anything, "t1", "job_1", mock.Anything).Return([]byte("Employee,Sales"), nil)
+
+	var out bytes.Buffer
+	err := exports.Download(context.Background(), "t1", "job_1", &out)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	err = exports.Download(WithPermissions(context.Background(), PermissionExportResponses), "t1", "job_1", &out)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Empty(t, out.String())
+
+	ctx := WithPermissions(context.Background(), PermissionExportResponses, PermissionViewIdentifiers)
+	assert.ErrorIs(t, exports.Download(ctx, "t1", "job_2", &out), repository.ErrConflict)
+	assert.ErrorIs(t, exports.Download(ctx, "t1", "expired", &out), repository.ErrNotFound)
+
+	assert.NoError(t, exports.Download(ctx, "t1", "job_1", &out))
+	assert.Equal(t, "Employee,Sales", out.String())
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// z95 is the normal quantile for a two-sided 95% confidence interval
+const z95 = 1.96
+
+// validateMetrics checks that every requested metric is computable
+func validateMetrics(verr *ValidationError, prefix string, specs []models.MetricSpec) {
+	for i, m := range specs {
+		field := fmt.Sprintf("%smetrics[%d]", prefix, i)
+		switch m.Type {
+		case models.MetricTypeENPS, models.MetricTypeFavourability, models.MetricTypeMean:
+		default:
+			verr.Add(field+".type", "invalid", "unknown metric type %q", m.Type)
+		}
+		if m.QuestionID == "" {
+			verr.Add(field+".question_id", "required", "question_id is required")
+		}
+		if top, bottom := m.Thresholds(); bottom >= top {
+			verr.Add(field+".unfavourable_max", "invalid_range", "unfavourable_max must be below favourable_min")
+		}
+	}
+}
+
+// validateTimeBucket checks the bucket granularity and time zone of trend queries
+func validateTimeBucket(verr *ValidationError, prefix string, q models.DashboardQuery) {
+	if q.TimeZone != "" {
+		if _, err := time.LoadLocation(q.TimeZone); err != nil {
+			verr.Add(prefix+"time_zone", "invalid", "unknown time zone %q", q.TimeZone)
+		}
+	}
+	if !contains(q.GroupBy, models.GroupByTimeBucket) {
+		return
+	}
+
+	switch q.TimeBucket {
+	case models.TimeBucketDay, models.TimeBucketWeek, models.TimeBucketMonth, models.TimeBucketQuarter:
+	default:
+		verr.Add(prefix+"time_bucket", "invalid", "invalid time_bucket %q: expected day, week, month or quarter", q.TimeBucket)
+	}
+}
+
+// validateAnswerFilters checks that each answer filter's value suits its operator
+func validateAnswerFilters(verr *ValidationError, prefix string, filters []models.AnswerFilter) {
+	for i, f := range filters {
+		field := fmt.Sprintf("%sanswer_filters[%d]", prefix, i)
+		if f.QuestionID == "" {
+			verr.Add(field+".question_id", "required", "question_id is required")
+		}
+
+		switch f.Op {
+		case models.FilterOpEq, models.FilterOpNe, models.FilterOpContains:
+			switch f.Value.(type) {
+			case string, float64, int, bool:
+			default:
+				verr.Add(field+".value", "invalid_type", "%s needs a string, number or boolean", f.Op)
+			}
+		case models.FilterOpLt, models.FilterOpLte, models.FilterOpGt, models.FilterOpGte:
+			switch f.Value.(type) {
+			case float64, int:
+			default:
+				verr.Add(field+".value", "invalid_type", "%s needs a number", f.Op)
+			}
+		case models.FilterOpIn:
+			if _, ok := f.Value.([]interface{}); !ok {
+				verr.Add(field+".value", "invalid_type", "in needs an array")
+			}
+		default:
+			verr.Add(field+".op", "invalid", "unknown operator %q", f.Op)
+		}
+	}
+}
+
+// validateBenchmarks checks benchmark scopes; parent units need an org group-by
+func validateBenchmarks(verr *ValidationError, prefix string, q models.DashboardQuery) {
+	for i, scope := range q.Benchmarks {
+		field := fmt.Sprintf("%sbenchmarks[%d]", prefix, i)
+		switch scope {
+		case models.BenchmarkCompany:
+		case models.BenchmarkParentUnit:
+			if !groupsByOrg(q.GroupBy) {
+				verr.Add(field, "invalid", "parent_unit requires grouping by department or unit_id")
+			}
+		default:
+			verr.Add(field, "invalid", "unknown benchmark scope %q", scope)
+		}
+	}
+}
+
+// finalizeMetrics derives values and confidence intervals from the raw SQL aggregates,
+// suppressing any metric with fewer respondents than the anonymity threshold. Group and
+// benchmark counts below the threshold are suppressed too, or they would publish the
+// size of a group whose metrics are hidden.
+func (s *DashboardService) finalizeMetrics(groups []models.GroupResult) {
+	for gi := range groups {
+		g := &groups[gi]
+		s.finalizeValues(g.Metrics)
+		for bi := range g.Benchmarks {
+			b := &g.Benchmarks[bi]
+			s.finalizeValues(b.Metrics)
+			b.Count, b.Suppressed = s.suppressCount(b.Count)
+		}
+		g.Count, g.Suppressed = s.suppressCount(g.Count)
+	}
+}
+
+// suppressCount hides a population size below the anonymity threshold
+func (s *DashboardService) suppressCount(n int) (int, bool) {
+	if n < s.anonymityThreshold {
+		return 0, true
+	}
+	return n, false
+}
+
+func (s *DashboardService) finalizeValues(metrics []models.MetricValue) {
+	for mi := range metrics {
+		m := &metrics[mi]
+		if m.Respondents < s.anonymityThreshold || m.Respondents == 0 {
+			*m = models.MetricValue{Type: m.Type, QuestionID: m.QuestionID, Suppressed: true}
+			continue
+		}
+		computeMetric(m)
+	}
+}
+
+func computeMetric(m *models.MetricValue) {
+	n := float64(m.Respondents)
+	top := float64(m.Top) / n
+	bottom := float64(m.Bottom) / n
+
+	switch m.Type {
+	case models.MetricTypeENPS:
+		// Var(NPS) = (p + d - (p - d)^2) / n for promoter/detractor shares p, d
+		score := top - bottom
+		margin := z95 * math.Sqrt((top+bottom-score*score)/n)
+		setInterval(m, score*100, math.Max(-100, (score-margin)*100), math.Min(100, (score+margin)*100))
+		m.Breakdown = map[string]float64{
+			"promoters":  top * 100,
+			"passives":   (1 - top - bottom) * 100,
+			"detractors": bottom * 100,
+		}
+
+	case models.MetricTypeFavourability:
+		low, high := wilsonInterval(top, n)
+		setInterval(m, top*100, low*100, high*100)
+		m.Breakdown = map[string]float64{
+			"favourable":   top * 100,
+			"neutral":      (1 - top - bottom) * 100,
+			"unfavourable": bottom * 100,
+		}
+
+	case models.MetricTypeMean:
+		margin := 0.0
+		if m.Respondents > 1 {
+			margin = z95 * m.StdDev / math.Sqrt(n)
+		}
+		setInterval(m, m.Mean, m.Mean-margin, m.Mean+margin)
+	}
+}
+
+// wilsonInterval returns the 95% Wilson score interval for proportion p over n trials
+func wilsonInterval(p, n float64) (float64, float64) {
+	z2 := z95 * z95
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	margin := z95 * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / (1 + z2/n)
+	return center - margin, center + margin
+}
+
+func setInterval(m *models.MetricValue, value, low, high float64) {
+	m.Value = &value
+	m.CILow = &low
+	m.CIHigh = &high
+}
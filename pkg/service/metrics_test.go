@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestComputeENPS tests eNPS value, breakdown and interval bounds
+func TestComputeENPS(t *testing.T) {
+	m := &models.MetricValue{Type: models.MetricTypeENPS, Respondents: 100, Top: 50, Bottom: 20}
+
+	computeMetric(m)
+
+	assert.InDelta(t, 30, *m.Value, 0.001)
+	assert.InDelta(t, 30, m.Breakdown["passives"], 0.001)
+	// sqrt((0.5 + 0.2 - 0.09) / 100) * 1.96 ≈ 0.1531
+	assert.InDelta(t, 14.69, *m.CILow, 0.01)
+	assert.InDelta(t, 45.31, *m.CIHigh, 0.01)
+}
+
+// TestComputeFavourability tests the percentages and Wilson interval
+func TestComputeFavourability(t *testing.T) {
+	m := &models.MetricValue{Type: models.MetricTypeFavourability, Respondents: 20, Top: 20, Bottom: 0}
+
+	computeMetric(m)
+
+	assert.InDelta(t, 100, *m.Value, 0.001)
+	assert.InDelta(t, 0, m.Breakdown["unfavourable"], 0.001)
+	assert.Less(t, *m.CILow, 100.0) // Wilson stays informative at p = 1
+	assert.InDelta(t, 100, *m.CIHigh, 0.001)
+}
+
+// TestComputeMean tests the normal-approximation interval for means
+func TestComputeMean(t *testing.T) {
+	m := &models.MetricValue{Type: models.MetricTypeMean, Respondents: 16, Mean: 3.5, StdDev: 1}
+
+	computeMetric(m)
+
+	assert.InDelta(t, 3.5, *m.Value, 0.001)
+	assert.InDelta(t, 3.01, *m.CILow, 0.001)
+	assert.InDelta(t, 3.99, *m.CIHigh, 0.001)
+}
+
+// TestDashboardMetricsSuppression tests that small groups get no metric values
+func TestDashboardMetricsSuppression(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+
+	ctx := context.Background()
+	query := models.DashboardQuery{
+		TimeRange:  testTimeRange,
+		FilterMode: models.FilterModeHistorical,
+		GroupBy:    []string{"department"},
+		Metrics:    []models.MetricSpec{{Type: models.MetricTypeMean, QuestionID: "q1"}},
+	}
+	groups := []models.GroupResult{
+		{Key: map[string]string{"department": "Sales"}, Count: 10, Metrics: []models.MetricValue{
+			{Type: models.MetricTypeMean, QuestionID: "q1", Respondents: 10, Mean: 4, StdDev: 0.5},
+		}},
+		{Key: map[string]string{"department": "Legal"}, Count: 3, Metrics: []models.MetricValue{
+			{Type: models.MetricTypeMean, QuestionID: "q1", Respondents: 3, Mean: 2, StdDev: 0.5},
+		}},
+	}
+	mockResponseRepo.On("Query", ctx, query).Return([]models.Response{}, nil)
+	mockResponseRepo.On("Aggregate", ctx, query).Return(groups, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	assert.InDelta(t, 4, *result.Groups[0].Metrics[0].Value, 0.001)
+	assert.True(t, result.Groups[1].Metrics[0].Suppressed)
+	assert.Nil(t, result.Groups[1].Metrics[0].Value)
+	assert.Equal(t, 0, result.Groups[1].Metrics[0].Respondents)
+	assert.Equal(t, 10, result.Groups[0].Count)
+	assert.False(t, result.Groups[0].Suppressed)
+	assert.Equal(t, 0, result.Groups[1].Count)
+	assert.True(t, result.Groups[1].Suppressed)
+
+	_, err = service.Query(ctx, models.DashboardQuery{
+		TimeRange:  testTimeRange,
+		FilterMode: models.FilterModeHistorical,
+		Metrics:    []models.MetricSpec{{Type: "MEDIAN", QuestionID: "q1"}},
+	})
+	assert.Error(t, err)
+}
+
+// TestCurrentModeTrendUsesTodaysStructure tests that CURRENT trends group by current units
+func TestCurrentModeTrendUsesTodaysStructure(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(mockResponseRepo, mockOrgRepo, new(MockSurveyRepository))
+
+	ctx := context.Background()
+	query := models.DashboardQuery{
+		TimeRange:  testTimeRange,
+		FilterMode: models.FilterModeCurrent,
+		Filters:    map[string]interface{}{},
+		GroupBy:    []string{models.GroupByTimeBucket, "department"},
+		TimeBucket: models.TimeBucketQuarter,
+		TimeZone:   "Asia/Singapore",
+	}
+
+	mockResponseRepo.On("DistinctValues", ctx, mock.Anything, "unit_id").Return([]string{"unit_sales_old", "unit_revenue"}, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_sales_old").Return(&models.OrgUnitMapping{
+		RelationshipType: models.MappingTypeMerge,
+		TargetUnitIDs:    []string{"unit_revenue"},
+	}, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_revenue").Return(nil, nil)
+	mockOrgRepo.On("GetUnitByID", ctx, "unit_revenue").Return(&models.OrgUnit{UnitID: "unit_revenue", UnitName: "Revenue"}, nil)
+
+	withMapping := mock.MatchedBy(func(q models.DashboardQuery) bool {
+		return q.UnitMapping["unit_sales_old"] == "unit_revenue" && q.UnitMapping["unit_revenue"] == "unit_revenue"
+	})
+	mockResponseRepo.On("Query", ctx, withMapping).Return([]models.Response{}, nil)
+	mockResponseRepo.On("Aggregate", ctx, withMapping).Return([]models.GroupResult{
+		{Key: map[string]string{models.GroupByTimeBucket: "2024-01-01", "department": "unit_revenue"}, Count: 8},
+		{Key: map[string]string{models.GroupByTimeBucket: "2024-04-01", "department": "unit_revenue"}, Count: 9},
+	}, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Revenue", result.Groups[0].Key["department"])
+	assert.Equal(t, "Revenue", result.Groups[1].Key["department"])
+
+	query.TimeBucket = "fortnight"
+	_, err = service.Query(ctx, query)
+	assert.Error(t, err)
+}
+
+// TestDashboardBenchmarks tests that benchmark metrics are finalized and parent units map to today's structure
+func TestDashboardBenchmarks(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(mockResponseRepo, mockOrgRepo, new(MockSurveyRepository))
+
+	ctx := context.Background()
+	query := models.DashboardQuery{
+		TimeRange:  testTimeRange,
+		FilterMode: models.FilterModeHistorical,
+		GroupBy:    []string{"department"},
+		Metrics:    []models.MetricSpec{{Type: models.MetricTypeMean, QuestionID: "q1"}},
+		Benchmarks: []models.BenchmarkScope{models.BenchmarkCompany, models.BenchmarkParentUnit},
+	}
+
+	mockResponseRepo.On("DistinctValues", ctx, mock.Anything, "unit_id").Return([]string{"unit_eng"}, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_eng").Return(nil, nil)
+	mockOrgRepo.On("GetUnitByID", ctx, "unit_eng").Return(&models.OrgUnit{UnitID: "unit_eng", UnitName: "Engineering"}, nil)
+
+	mapped := mock.MatchedBy(func(q models.DashboardQuery) bool { return q.UnitMapping["unit_eng"] == "unit_eng" })
+	mockResponseRepo.On("Query", ctx, mapped).Return([]models.Response{}, nil)
+	mockResponseRepo.On("Aggregate", ctx, mapped).Return([]models.GroupResult{{
+		Key:     map[string]string{"department": "unit_eng"},
+		Count:   10,
+		Metrics: []models.MetricValue{{Type: models.MetricTypeMean, QuestionID: "q1", Respondents: 10, Mean: 4, StdDev: 1}},
+		Benchmarks: []models.Benchmark{
+			{Scope: models.BenchmarkCompany, Count: 500, Metrics: []models.MetricValue{{Type: models.MetricTypeMean, QuestionID: "q1", Respondents: 500, Mean: 3.6, StdDev: 1}}},
+			{Scope: models.BenchmarkParentUnit, UnitID: "unit_rnd", Count: 3, Metrics: []models.MetricValue{{Type: models.MetricTypeMean, QuestionID: "q1", Respondents: 3, Mean: 3.9}}},
+		},
+	}}, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	group := result.Groups[0]
+	assert.Equal(t, "Engineering", group.Key["department"])
+	assert.InDelta(t, 3.6, *group.Benchmarks[0].Metrics[0].Value, 0.001)
+	assert.True(t, group.Benchmarks[1].Metrics[0].Suppressed)
+	assert.Equal(t, 500, group.Benchmarks[0].Count)
+	assert.True(t, group.Benchmarks[1].Suppressed)
+	assert.Equal(t, 0, group.Benchmarks[1].Count)
+
+	query.GroupBy = []string{"age_band"}
+	_, err = service.Query(ctx, query)
+	assert.Error(t, err)
+}
+
+// TestValidateAnswerFilters tests type-aware operator checks on answer filters
+func TestValidateAnswerFilters(t *testing.T) {
+	validateAnswerFilters := func(filters []models.AnswerFilter) error {
+		verr := &ValidationError{}
+		validateAnswerFilters(verr, "", filters)
+		return verr.OrNil()
+	}
+
+	assert.NoError(t, validateAnswerFilters([]models.AnswerFilter{
+		{QuestionID: "q3", Op: models.FilterOpLte, Value: 2.0},
+		{QuestionID: "q4", Op: models.FilterOpEq, Value: "Yes"},
+		{QuestionID: "q5", Op: models.FilterOpIn, Value: []interface{}{"a", "b"}},
+		{QuestionID: "q6", Op: models.FilterOpContains, Value: "remote"},
+	}))
+
+	assert.Error(t, validateAnswerFilters([]models.AnswerFilter{{QuestionID: "q3", Op: models.FilterOpLte, Value: "2"}}))
+	assert.Error(t, validateAnswerFilters([]models.AnswerFilter{{QuestionID: "q3", Op: models.FilterOpIn, Value: 2.0}}))
+	assert.Error(t, validateAnswerFilters([]models.AnswerFilter{{QuestionID: "q3", Op: "like", Value: "x"}}))
+	assert.Error(t, validateAnswerFilters([]models.AnswerFilter{{Op: models.FilterOpEq, Value: 1.0}}))
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// ErrSurveyNotLaunched is returned for participation of a survey with no eligible population yet
+var ErrSurveyNotLaunched = repository.NewKindError(repository.ErrConflict, "survey has not been launched")
+
+// ParticipationByUnit returns invited, responded and response rate per org unit, with
+// subtree rollups. HISTORICAL mode uses the structure at launch; CURRENT mode maps
+// each launch-time unit forward to today's structure through org mappings.
+func (s *DashboardService) ParticipationByUnit(ctx context.Context, tenantID, surveyID string, mode models.FilterMode) (*models.ParticipationResult, error) {
+	if mode != models.FilterModeHistorical && mode != models.FilterModeCurrent {
+		return nil, fmt.Errorf("%w: invalid filter mode: %s", ErrInvalidRequest, mode)
+	}
+
+	survey, err := s.surveyRepo.GetByID(ctx, tenantID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey == nil {
+		return nil, fmt.Errorf("survey %w: %s", repository.ErrNotFound, surveyID)
+	}
+	if survey.LaunchedAt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSurveyNotLaunched, surveyID)
+	}
+
+	counts, err := s.surveyRepo.GetParticipationByUnit(ctx, tenantID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &unitTree{
+		dashboard: s,
+		mode:      mode,
+		asOf:      *survey.LaunchedAt,
+		units:     make(map[string]*unitNode),
+	}
+
+	var total models.UnitResponseCount
+	for _, c := range counts {
+		total.Invited += c.Invited
+		total.Responded += c.Responded
+
+		unitID, mapped := c.UnitID, true
+		if mode == models.FilterModeCurrent && unitID != "" {
+			unitID, mapped, err = s.orgMapper.MapHistoricalToCurrent(ctx, c.UnitID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to map unit %s: %w", c.UnitID, err)
+			}
+		}
+		if err := tree.add(ctx, unitID, mapped, c); err != nil {
+			return nil, err
+		}
+	}
+
+	totalHidden := tree.suppress(s.anonymityThreshold, total)
+
+	result := &models.ParticipationResult{
+		SurveyID:           surveyID,
+		FilterMode:         mode,
+		Total:              participationCounts(total, totalHidden),
+		AnonymityThreshold: s.anonymityThreshold,
+	}
+	for _, n := range tree.sorted() {
+		result.Units = append(result.Units, models.UnitParticipation{
+			UnitID:       n.unitID,
+			UnitName:     n.unitName,
+			ParentUnitID: n.parentID,
+			Direct:       participationCounts(n.direct, n.directHidden),
+			Subtree:      participationCounts(n.subtree, n.subtreeHidden),
+			Unmapped:     !n.mapped,
+		})
+	}
+
+	return result, nil
+}
+
+// participationCounts hides responded and rate for suppressed groups
+func participationCounts(c models.UnitResponseCount, suppressed bool) models.ParticipationCounts {
+	if suppressed {
+		return models.ParticipationCounts{Invited: c.Invited, Suppressed: true}
+	}
+
+	responded := c.Responded
+	rate := float64(c.Responded) / float64(c.Invited)
+	return models.ParticipationCounts{Invited: c.Invited, Responded: &responded, Rate: &rate}
+}
+
+// unitNode accumulates counts for one unit of the reporting structure
+type unitNode struct {
+	unitID   string
+	unitName string
+	parentID *string
+	path     string
+	mapped   bool
+	direct   models.UnitResponseCount
+	subtree  models.UnitResponseCount
+
+	directHidden  bool
+	subtreeHidden bool
+}
+
+// unitTree builds the reporting structure lazily from the org repository
+type unitTree struct {
+	dashboard *DashboardService
+	mode      models.FilterMode
+	asOf      time.Time
+	units     map[string]*unitNode
+}
+
+// add records direct counts on unitID and rolls them up through every ancestor
+func (t *unitTree) add(ctx context.Context, unitID string, mapped bool, c models.UnitResponseCount) error {
+	node, err := t.node(ctx, unitID)
+	if err != nil {
+		return err
+	}
+	node.mapped = node.mapped && mapped
+	node.direct.Invited += c.Invited
+	node.direct.Responded += c.Responded
+
+	visited := make(map[string]bool)
+	for node != nil && !visited[node.unitID] {
+		visited[node.unitID] = true
+		node.subtree.Invited += c.Invited
+		node.subtree.Responded += c.Responded
+
+		if node.parentID == nil {
+			break
+		}
+		if node, err = t.node(ctx, *node.parentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *unitTree) node(ctx context.Context, unitID string) (*unitNode, error) {
+	if n, ok := t.units[unitID]; ok {
+		return n, nil
+	}
+
+	n := &unitNode{unitID: unitID, unitName: unitID, mapped: true}
+	if unitID != "" {
+		var unit *models.OrgUnit
+		var err error
+		if t.mode == models.FilterModeCurrent {
+			unit, err = t.dashboard.orgRepo.GetUnitByID(ctx, unitID)
+		} else {
+			unit, err = t.dashboard.orgRepo.GetUnitAtTime(ctx, unitID, t.asOf)
+		}
+		// Units missing from the structure (e.g. dissolved) are reported as roots
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to load unit %s: %w", unitID, err)
+		}
+		if err == nil && unit != nil {
+			n.unitName = unit.UnitName
+			n.parentID = unit.ParentUnitID
+			n.path = unit.Path
+		}
+	}
+
+	t.units[unitID] = n
+	return n, nil
+}
+
+// hiddenCell is one published responded count that may be suppressed
+type hiddenCell struct {
+	key     string
+	invited int
+	hidden  *bool
+}
+
+// protects reports whether hiding the cell withholds anything
+func (c hiddenCell) protects() bool {
+	return *c.hidden && c.invited > 0
+}
+
+// suppress decides which counts to hide and reports whether the total is hidden.
+// Counts below the anonymity threshold are hidden first. Because a unit's subtree is
+// its direct count plus its children's subtrees, and the total is the sum of the root
+// subtrees, a single hidden count in any such sum could be recovered by subtraction.
+// Complementary suppression therefore hides the smallest visible count in the sum (or
+// the sum itself) until every sum hides either none or at least two of its counts.
+// Empty groups are known to be zero, so hiding them protects nothing.
+func (t *unitTree) suppress(threshold int, total models.UnitResponseCount) bool {
+	totalHidden := total.Invited < threshold
+
+	nodes := t.sorted()
+	children := make(map[string][]hiddenCell)
+	var roots []hiddenCell
+	for _, n := range nodes {
+		n.directHidden = n.direct.Invited < threshold
+		n.subtreeHidden = n.subtree.Invited < threshold
+
+		cell := hiddenCell{key: n.unitID, invited: n.subtree.Invited, hidden: &n.subtreeHidden}
+		if n.parentID == nil || t.units[*n.parentID] == nil {
+			roots = append(roots, cell)
+		} else {
+			children[*n.parentID] = append(children[*n.parentID], cell)
+		}
+	}
+
+	type sum struct {
+		total hiddenCell
+		parts []hiddenCell
+	}
+	sums := []sum{{total: hiddenCell{invited: total.Invited, hidden: &totalHidden}, parts: roots}}
+	for _, n := range nodes {
+		direct := hiddenCell{invited: n.direct.Invited, hidden: &n.directHidden}
+		sums = append(sums, sum{
+			total: hiddenCell{key: n.unitID, invited: n.subtree.Invited, hidden: &n.subtreeHidden},
+			parts: append([]hiddenCell{direct}, children[n.unitID]...),
+		})
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, sm := range sums {
+			hidden := 0
+			if sm.total.protects() {
+				hidden++
+			}
+			for _, p := range sm.parts {
+				if p.protects() {
+					hidden++
+				}
+			}
+			if hidden != 1 {
+				continue
+			}
+
+			var pick *hiddenCell
+			for i := range sm.parts {
+				p := &sm.parts[i]
+				if *p.hidden || p.invited == 0 {
+					continue
+				}
+				if pick == nil || p.invited < pick.invited || (p.invited == pick.invited && p.key < pick.key) {
+					pick = p
+				}
+			}
+			if pick == nil {
+				pick = &sm.total
+			}
+			*pick.hidden = true
+			changed = true
+		}
+	}
+
+	return totalHidden
+}
+
+// sorted returns units in hierarchy order (by path, then ID)
+func (t *unitTree) sorted() []*unitNode {
+	nodes := make([]*unitNode, 0, len(t.units))
+	for _, n := range t.units {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].path != nodes[j].path {
+			return nodes[i].path < nodes[j].path
+		}
+		return nodes[i].unitID < nodes[j].unitID
+	})
+	return nodes
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestParticipationByUnitCurrent tests forward mapping, subtree rollups and suppression
+func TestParticipationByUnitCurrent(t *testing.T) {
+	mockSurveyRepo := new(MockSurveyRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(new(MockResponseRepository), mockOrgRepo, mockSurveyRepo)
+
+	ctx := context.Background()
+	launched := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := "unit_root"
+
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "survey_001").
+		Return(&models.Survey{SurveyID: "survey_001", LaunchedAt: &launched}, nil)
+	mockSurveyRepo.On("GetParticipationByUnit", ctx, "tenant_demo", "survey_001").Return([]models.UnitResponseCount{
+		{UnitID: "unit_sales_old", Invited: 6, Responded: 3}, // Merged into unit_revenue
+		{UnitID: "unit_revenue", Invited: 4, Responded: 2},
+		{UnitID: "unit_legal", Invited: 2, Responded: 2},
+		{UnitID: "unit_ops", Invited: 5, Responded: 3}, // Hidden to protect unit_legal
+	}, nil)
+
+	mockOrgRepo.On("GetMapping", ctx, "unit_sales_old").Return(&models.OrgUnitMapping{
+		RelationshipType: models.MappingTypeMerge,
+		TargetUnitIDs:    []string{"unit_revenue"},
+	}, nil)
+	mockOrgRepo.On("GetMapping", ctx, mock.Anything).Return(nil, nil)
+	mockOrgRepo.On("GetUnitByID", ctx, "unit_revenue").Return(&models.OrgUnit{UnitID: "unit_revenue", UnitName: "Revenue", ParentUnitID: &root, Path: "root.revenue"}, nil)
+	mockOrgRepo.On("GetUnitByID", ctx, "unit_legal").Return(&models.OrgUnit{UnitID: "unit_legal", UnitName: "Legal", ParentUnitID: &root, Path: "root.legal"}, nil)
+	mockOrgRepo.On("GetUnitByID", ctx, "unit_ops").Return(&models.OrgUnit{UnitID: "unit_ops", UnitName: "Operations", ParentUnitID: &root, Path: "root.ops"}, nil)
+	mockOrgRepo.On("GetUnitByID", ctx, "unit_root").Return(&models.OrgUnit{UnitID: "unit_root", UnitName: "Company", Path: "root"}, nil)
+
+	result, err := service.ParticipationByUnit(ctx, "tenant_demo", "survey_001", models.FilterModeCurrent)
+	assert.NoError(t, err)
+
+	units := make(map[string]models.UnitParticipation)
+	for _, u := range result.Units {
+		units[u.UnitID] = u
+	}
+	assert.Len(t, units, 4)
+
+	revenue := units["unit_revenue"]
+	assert.Equal(t, 10, revenue.Direct.Invited)
+	assert.Equal(t, 5, *revenue.Direct.Responded)
+	assert.InDelta(t, 0.5, *revenue.Direct.Rate, 0.001)
+
+	legal := units["unit_legal"]
+	assert.True(t, legal.Direct.Suppressed)
+	assert.Nil(t, legal.Direct.Responded)
+	assert.Nil(t, legal.Direct.Rate)
+	assert.True(t, units["unit_ops"].Subtree.Suppressed)
+
+	company := units["unit_root"]
+	assert.Equal(t, 0, company.Direct.Invited)
+	assert.Equal(t, 17, company.Subtree.Invited)
+	assert.Equal(t, 10, *company.Subtree.Responded)
+	assert.Equal(t, 17, result.Total.Invited)
+}
+
+// TestParticipationComplementarySuppression tests that a suppressed unit cannot be
+// recovered by subtracting its visible siblings from the parent's subtree
+func TestParticipationComplementarySuppression(t *testing.T) {
+	mockSurveyRepo := new(MockSurveyRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(new(MockResponseRepository), mockOrgRepo, mockSurveyRepo)
+
+	ctx := context.Background()
+	launched := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := "unit_root"
+
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "survey_001").
+		Return(&models.Survey{SurveyID: "survey_001", LaunchedAt: &launched}, nil)
+	mockSurveyRepo.On("GetParticipationByUnit", ctx, "tenant_demo", "survey_001").Return([]models.UnitResponseCount{
+		{UnitID: "unit_eng", Invited: 10, Responded: 6},
+		{UnitID: "unit_sales", Invited: 8, Responded: 4},
+		{UnitID: "unit_legal", Invited: 3, Responded: 1},
+	}, nil)
+	for _, id := range []string{"unit_eng", "unit_sales", "unit_legal"} {
+		mockOrgRepo.On("GetUnitAtTime", ctx, id, launched).
+			Return(&models.OrgUnit{UnitID: id, UnitName: id, ParentUnitID: &root, Path: "root." + id}, nil)
+	}
+	mockOrgRepo.On("GetUnitAtTime", ctx, "unit_root", launched).
+		Return(&models.OrgUnit{UnitID: "unit_root", UnitName: "Company", Path: "root"}, nil)
+
+	result, err := service.ParticipationByUnit(ctx, "tenant_demo", "survey_001", models.FilterModeHistorical)
+	assert.NoError(t, err)
+
+	units := make(map[string]models.UnitParticipation)
+	for _, u := range result.Units {
+		units[u.UnitID] = u
+	}
+
+	// Legal is below the threshold; sales is the smallest sibling left to hide
+	assert.True(t, units["unit_legal"].Subtree.Suppressed)
+	assert.True(t, units["unit_sales"].Subtree.Suppressed)
+	assert.True(t, units["unit_sales"].Direct.Suppressed)
+	assert.Nil(t, units["unit_sales"].Subtree.Responded)
+	assert.False(t, units["unit_eng"].Subtree.Suppressed)
+	assert.Equal(t, 6, *units["unit_eng"].Subtree.Responded)
+	assert.Equal(t, 11, *units["unit_root"].Subtree.Responded)
+	assert.Equal(t, 11, *result.Total.Responded)
+}
+
+// TestParticipationByUnitOrgErrors tests that missing units become roots and other
+// repository errors are returned
+func TestParticipationByUnitOrgErrors(t *testing.T) {
+	mockSurveyRepo := new(MockSurveyRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewDashboardService(new(MockResponseRepository), mockOrgRepo, mockSurveyRepo)
+
+	ctx := context.Background()
+	launched := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "survey_001").
+		Return(&models.Survey{SurveyID: "survey_001", LaunchedAt: &launched}, nil)
+	mockSurveyRepo.On("GetParticipationByUnit", ctx, "tenant_demo", "survey_001").Return([]models.UnitResponseCount{
+		{UnitID: "unit_dissolved", Invited: 6, Responded: 3},
+	}, nil)
+	mockOrgRepo.On("GetUnitAtTime", ctx, "unit_dissolved", launched).
+		Return(nil, fmt.Errorf("org unit %w", repository.ErrNotFound)).Once()
+
+	result, err := service.ParticipationByUnit(ctx, "tenant_demo", "survey_001", models.FilterModeHistorical)
+	assert.NoError(t, err)
+	assert.Len(t, result.Units, 1)
+	assert.Nil(t, result.Units[0].ParentUnitID)
+
+	dbErr := errors.New("connection reset")
+	mockOrgRepo.On("GetUnitAtTime", ctx, "unit_dissolved", launched).Return(nil, dbErr)
+
+	_, err = service.ParticipationByUnit(ctx, "tenant_demo", "survey_001", models.FilterModeHistorical)
+	assert.ErrorIs(t, err, dbErr)
+}
+
+// TestMapHistoricalToCurrent tests forward mapping chains
+func TestMapHistoricalToCurrent(t *testing.T) {
+	mockOrgRepo := new(MockOrgRepository)
+	mapper := NewOrgMapper(mockOrgRepo)
+	ctx := context.Background()
+
+	mockOrgRepo.On("GetMapping", ctx, "a").Return(&models.OrgUnitMapping{RelationshipType: models.MappingTypeRename, TargetUnitIDs: []string{"a"}}, nil)
+	mockOrgRepo.On("GetMapping", ctx, "b").Return(&models.OrgUnitMapping{RelationshipType: models.MappingTypeMerge, TargetUnitIDs: []string{"c"}}, nil)
+	mockOrgRepo.On("GetMapping", ctx, "c").Return(&models.OrgUnitMapping{RelationshipType: models.MappingTypeSplit, TargetUnitIDs: []string{"d", "e"}}, nil)
+
+	unit, mapped, err := mapper.MapHistoricalToCurrent(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", unit)
+	assert.True(t, mapped)
+
+	unit, mapped, err = mapper.MapHistoricalToCurrent(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", unit)
+	assert.False(t, mapped)
+}
+
+// TestMapCurrentToHistorical tests the reverse lookup from a renamed unit's current name
+func TestMapCurrentToHistorical(t *testing.T) {
+	mockOrgRepo := new(MockOrgRepository)
+	mapper := NewOrgMapper(mockOrgRepo)
+	ctx := context.Background()
+
+	// unit_sales was renamed Revenue (keeping its ID) and absorbed unit_emea. unit_old
+	// was split, and unit_apac was merged in but later moved on to unit_asia.
+	rename := models.OrgUnitMapping{SourceUnitID: "unit_sales", RelationshipType: models.MappingTypeRename, TargetUnitIDs: []string{"unit_sales"}}
+	merge := models.OrgUnitMapping{SourceUnitID: "unit_emea", RelationshipType: models.MappingTypeMerge, TargetUnitIDs: []string{"unit_sales"}}
+	split := models.OrgUnitMapping{SourceUnitID: "unit_old", RelationshipType: models.MappingTypeSplit, TargetUnitIDs: []string{"unit_sales", "unit_ops"}}
+	moved := models.OrgUnitMapping{SourceUnitID: "unit_apac", RelationshipType: models.MappingTypeMerge, TargetUnitIDs: []string{"unit_sales"}}
+
+	mockOrgRepo.On("FindCurrentUnitsByName", ctx, "Revenue").Return([]models.OrgUnit{{UnitID: "unit_sales", UnitName: "Revenue"}}, nil)
+	mockOrgRepo.On("FindCurrentUnitsByName", ctx, "Sales").Return([]models.OrgUnit(nil), nil)
+	mockOrgRepo.On("FindMappingsByTarget", ctx, "unit_sales").Return([]models.OrgUnitMapping{rename, merge, split, moved}, nil)
+	mockOrgRepo.On("FindMappingsByTarget", ctx, mock.Anything).Return([]models.OrgUnitMapping(nil), nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_sales").Return(&rename, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_emea").Return(&merge, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_old").Return(&split, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_apac").Return(&models.OrgUnitMapping{
+		SourceUnitID: "unit_apac", RelationshipType: models.MappingTypeMerge, TargetUnitIDs: []string{"unit_asia"},
+	}, nil)
+	mockOrgRepo.On("GetMapping", ctx, "unit_asia").Return(nil, nil)
+
+	unitIDs, err := mapper.MapCurrentToHistorical(ctx, "tenant_demo", "Revenue")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"unit_emea", "unit_sales"}, unitIDs)
+
+	// The old name no longer selects anything
+	unitIDs, err = mapper.MapCurrentToHistorical(ctx, "tenant_demo", "Sales")
+	assert.NoError(t, err)
+	assert.Empty(t, unitIDs)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+const (
+	// DefaultPartitionCheckInterval is how often the manager checks the partition layout
+	DefaultPartitionCheckInterval = 6 * time.Hour
+	// DefaultPartitionsAhead is how many future months get a partition in advance
+	DefaultPartitionsAhead = 3
+)
+
+// ErrPartitionNotFound is returned when attaching or detaching a month without a partition
+var ErrPartitionNotFound = repository.NewKindError(repository.ErrNotFound, "partition not found")
+
+// PartitionManager keeps survey_responses partitioned by month: future partitions are
+// created ahead of time and, with a retention set, old ones are detached for archival.
+// Like the refresh scheduler, every replica runs one and only the lock holder acts.
+type PartitionManager struct {
+	partitionRepo repository.PartitionRepository
+	leader        repository.LeaderLock
+	interval      time.Duration
+	ahead         int
+	retention     int // Months kept attached, including the current one; 0 keeps everything
+	now           func() time.Time
+}
+
+func NewPartitionManager(partitionRepo repository.PartitionRepository, leader repository.LeaderLock) *PartitionManager {
+	return &PartitionManager{
+		partitionRepo: partitionRepo,
+		leader:        leader,
+		interval:      DefaultPartitionCheckInterval,
+		ahead:         DefaultPartitionsAhead,
+		now:           time.Now,
+	}
+}
+
+// SetPolicy overrides how many months are created ahead and how many stay attached
+func (m *PartitionManager) SetPolicy(interval time.Duration, ahead, retention int) {
+	m.interval = interval
+	m.ahead = ahead
+	m.retention = retention
+}
+
+// Run maintains partitions until ctx is cancelled, then gives up leadership
+func (m *PartitionManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if leader, err := m.leader.TryAcquire(ctx); err != nil {
+			log.Printf("partition manager: %v", err)
+		} else if leader {
+			if _, err := m.Maintain(ctx); err != nil {
+				log.Printf("partition manager: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := m.leader.Release(context.Background()); err != nil {
+				log.Printf("partition manager: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Maintain creates missing partitions from the current month through m.ahead months
+// out, then detaches attached partitions older than the retention window
+func (m *PartitionManager) Maintain(ctx context.Context) (*models.PartitionReport, error) {
+	partitions, err := m.partitionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(partitions))
+	for _, p := range partitions {
+		existing[p.Name] = true
+	}
+
+	report := &models.PartitionReport{Created: []string{}, Detached: []string{}}
+	current := monthStart(m.now().UTC())
+
+	for i := 0; i <= m.ahead; i++ {
+		month := current.AddDate(0, i, 0)
+		name := repository.PartitionName(month)
+		if existing[name] {
+			continue
+		}
+		if err := m.partitionRepo.Create(ctx, month); err != nil {
+			return report, err
+		}
+		report.Created = append(report.Created, name)
+	}
+
+	if m.retention <= 0 {
+		return report, nil
+	}
+	cutoff := current.AddDate(0, 1-m.retention, 0)
+	for _, p := range partitions {
+		if !p.Attached || !p.Month.Before(cutoff) {
+			continue
+		}
+		if err := m.partitionRepo.Detach(ctx, p.Month); err != nil {
+			return report, err
+		}
+		report.Detached = append(report.Detached, p.Name)
+	}
+
+	return report, nil
+}
+
+// Attach re-attaches the detached partition holding month, e.g. to restore archived data
+func (m *PartitionManager) Attach(ctx context.Context, month time.Time) error {
+	p, err := m.find(ctx, month)
+	if err != nil {
+		return err
+	}
+	if p.Attached {
+		return nil
+	}
+	return m.partitionRepo.Attach(ctx, p.Month)
+}
+
+// Detach detaches the partition holding month, leaving it as a standalone table
+func (m *PartitionManager) Detach(ctx context.Context, month time.Time) error {
+	p, err := m.find(ctx, month)
+	if err != nil {
+		return err
+	}
+	if !p.Attached {
+		return nil
+	}
+	return m.partitionRepo.Detach(ctx, p.Month)
+}
+
+// List returns every monthly partition, oldest first
+func (m *PartitionManager) List(ctx context.Context) ([]models.ResponsePartition, error) {
+	return m.partitionRepo.List(ctx)
+}
+
+// find looks up the partition holding month
+func (m *PartitionManager) find(ctx context.Context, month time.Time) (*models.ResponsePartition, error) {
+	partitions, err := m.partitionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := repository.PartitionName(month)
+	for i := range partitions {
+		if partitions[i].Name == name {
+			return &partitions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPartitionNotFound, name)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPartitionRepository is a mock implementation for testing
+type MockPartitionRepository struct {
+	mock.Mock
+}
+
+func (m *MockPartitionRepository) List(ctx context.Context) ([]models.ResponsePartition, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.ResponsePartition), args.Error(1)
+}
+
+func (m *MockPartitionRepository) Create(ctx context.Context, month time.Time) error {
+	args := m.Called(ctx, month)
+	return args.Error(0)
+}
+
+func (m *MockPartitionRepository) Attach(ctx context.Context, month time.Time) error {
+	args := m.Called(ctx, month)
+	return args.Error(0)
+}
+
+func (m *MockPartitionRepository) Detach(ctx context.Context, month time.Time) error {
+	args := m.Called(ctx, month)
+	return args.Error(0)
+}
+
+func (m *MockPartitionRepository) Tenants(ctx context.Context, month time.Time) ([]string, error) {
+	args := m.Called(ctx, month)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockPartitionRepository) Responses(ctx context.Context, month time.Time, tenantID string) ([]models.Response, error) {
+	args := m.Called(ctx, month, tenantID)
+	return args.Get(0).([]models.Response), args.Error(1)
+}
+
+func (m *MockPartitionRepository) Drop(ctx context.Context, month time.Time) error {
+	args := m.Called(ctx, month)
+	return args.Error(0)
+}
+
+func partition(year int, month time.Month, attached bool) models.ResponsePartition {
+	m := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return models.ResponsePartition{Name: "survey_responses_p" + m.Format("200601"), Month: m, Attached: attached}
+}
+
+// TestPartitionMaintain tests that future partitions are created and old ones detached
+func TestPartitionMaintain(t *testing.T) {
+	mockRepo := new(MockPartitionRepository)
+	manager := NewPartitionManager(mockRepo, new(MockLeaderLock))
+	manager.SetPolicy(DefaultPartitionCheckInterval, 2, 3)
+	manager.now = func() time.Time { return time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	mockRepo.On("List", ctx).Return([]models.ResponsePartition{
+		partition(2024, 2, false),
+		partition(2024, 3, true),
+		partition(2024, 4, true),
+		partition(2024, 5, true),
+		partition(2024, 6, true),
+	}, nil)
+	mockRepo.On("Create", ctx, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)).Return(nil)
+	mockRepo.On("Create", ctx, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)).Return(nil)
+	mockRepo.On("Detach", ctx, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)).Return(nil)
+
+	report, err := manager.Maintain(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"survey_responses_p202407", "survey_responses_p202408"}, report.Created)
+	// April through June stay attached; February is already detached
+	assert.Equal(t, []string{"survey_responses_p202403"}, report.Detached)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestPartitionAttachUnknownMonth tests attaching a month that has no partition
+func TestPartitionAttachUnknownMonth(t *testing.T) {
+	mockRepo := new(MockPartitionRepository)
+	manager := NewPartitionManager(mockRepo, new(MockLeaderLock))
+	ctx := context.Background()
+
+	mockRepo.On("List", ctx).Return([]models.ResponsePartition{partition(2024, 2, false)}, nil)
+	mockRepo.On("Attach", ctx, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)).Return(nil)
+
+	assert.NoError(t, manager.Attach(ctx, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+	assert.ErrorIs(t, manager.Attach(ctx, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)), ErrPartitionNotFound)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// summaryAttributes are the snapshot_core keys mv_department_summary is grouped by
+var summaryAttributes = map[string]bool{
+	"department":        true,
+	"performance_grade": true,
+}
+
+// planQuery decides whether a query can be answered from mv_department_summary, which
+// holds active-response counts per tenant, survey, department, grade and UTC month.
+// The reason explains why the base table is needed otherwise.
+func planQuery(q models.DashboardQuery) (models.QueryPath, string) {
+	switch {
+	case q.FilterMode != models.FilterModeHistorical:
+		return models.QueryPathBaseTable, "view holds historical snapshot attributes only"
+	case len(q.GroupBy) == 0:
+		return models.QueryPathBaseTable, "response rows requested"
+	case len(q.Metrics) > 0 || len(q.Benchmarks) > 0:
+		return models.QueryPathBaseTable, "view holds counts only"
+	case len(q.AnswerFilters) > 0:
+		return models.QueryPathBaseTable, "view has no answers"
+	case q.IncludeWithdrawn:
+		return models.QueryPathBaseTable, "view holds active responses only"
+	case q.UnitMapping != nil:
+		return models.QueryPathBaseTable, "view has no unit IDs"
+	case len(q.Archived) > 0:
+		return models.QueryPathBaseTable, "time range includes archived months"
+	}
+
+	for field := range q.Filters {
+		if !summaryAttributes[field] {
+			return models.QueryPathBaseTable, fmt.Sprintf("view has no %s filter", field)
+		}
+	}
+
+	for _, field := range q.GroupBy {
+		switch {
+		case summaryAttributes[field], field == models.GroupBySurvey:
+		case field == models.GroupByTimeBucket:
+			if q.TimeBucket != models.TimeBucketMonth || (q.TimeZone != "" && q.TimeZone != "UTC") {
+				return models.QueryPathBaseTable, "view buckets by UTC month only"
+			}
+		default:
+			return models.QueryPathBaseTable, fmt.Sprintf("view has no %s dimension", field)
+		}
+	}
+
+	if !monthAligned(q.TimeRange) {
+		return models.QueryPathBaseTable, "time range does not cover whole UTC months"
+	}
+
+	return models.QueryPathMaterializedView, ""
+}
+
+// monthAligned reports whether r starts on a UTC month boundary and ends in the last
+// second of a month, so whole view rows fall inside it
+func monthAligned(r models.TimeRange) bool {
+	from := r.From.UTC()
+	if from.IsZero() || !from.Equal(monthStart(from)) {
+		return false
+	}
+
+	end := r.To.UTC().Add(time.Second)
+	return r.To.After(r.From) && end.Sub(monthStart(end)) < time.Second
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// executeSummary answers a qualifying query from the materialized view. The view has
+// no response rows, so Count is the sum over groups and Responses stays empty. Group
+// counts below the anonymity threshold are suppressed after summing.
+func (s *DashboardService) executeSummary(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
+	groups, err := s.responseRepo.AggregateSummary(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DashboardResult{
+		Responses: []models.Response{},
+		Groups:    groups,
+		Metadata:  &models.QueryMetadata{Path: models.QueryPathMaterializedView},
+	}
+	for _, g := range groups {
+		result.Count += g.Count
+	}
+	s.finalizeMetrics(groups)
+
+	if s.refreshRepo != nil {
+		last, err := s.refreshRepo.LastRefresh(ctx, repository.SummaryViewName)
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			result.Metadata.DataAsOf = &last.DataAsOf
+		}
+	}
+	return result, nil
+}
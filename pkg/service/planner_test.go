@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func summaryQuery() models.DashboardQuery {
+	return models.DashboardQuery{
+		FilterMode: models.FilterModeHistorical,
+		Filters:    map[string]interface{}{"department": "Sales"},
+		GroupBy:    []string{"performance_grade", models.GroupByTimeBucket},
+		TimeBucket: models.TimeBucketMonth,
+		TimeRange: models.TimeRange{
+			From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+		},
+	}
+}
+
+// TestPlanQuery tests which queries qualify for the materialized view
+func TestPlanQuery(t *testing.T) {
+	path, _ := planQuery(summaryQuery())
+	assert.Equal(t, models.QueryPathMaterializedView, path)
+
+	tests := map[string]func(q *models.DashboardQuery){
+		"current mode":    func(q *models.DashboardQuery) { q.FilterMode = models.FilterModeCurrent },
+		"metrics":         func(q *models.DashboardQuery) { q.Metrics = []models.MetricSpec{{Type: models.MetricTypeMean}} },
+		"age band filter": func(q *models.DashboardQuery) { q.Filters["age_band"] = "25-34" },
+		"weekly buckets":  func(q *models.DashboardQuery) { q.TimeBucket = models.TimeBucketWeek },
+		"mid-month start": func(q *models.DashboardQuery) { q.TimeRange.From = q.TimeRange.From.AddDate(0, 0, 14) },
+		"next month end":  func(q *models.DashboardQuery) { q.TimeRange.To = q.TimeRange.To.Add(time.Second) },
+		"withdrawn":       func(q *models.DashboardQuery) { q.IncludeWithdrawn = true },
+	}
+	for name, modify := range tests {
+		q := summaryQuery()
+		modify(&q)
+		path, reason := planQuery(q)
+		assert.Equal(t, models.QueryPathBaseTable, path, name)
+		assert.NotEmpty(t, reason, name)
+	}
+}
+
+// TestQueryServedFromSummary tests that qualifying queries skip the base table
+func TestQueryServedFromSummary(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+
+	ctx := context.Background()
+	query := summaryQuery()
+	mockResponseRepo.On("AggregateSummary", ctx, query).Return([]models.GroupResult{
+		{Key: map[string]string{"performance_grade": "A", models.GroupByTimeBucket: "2024-01-01"}, Count: 12},
+		{Key: map[string]string{"performance_grade": "B", models.GroupByTimeBucket: "2024-01-01"}, Count: 30},
+		{Key: map[string]string{"performance_grade": "C", models.GroupByTimeBucket: "2024-01-01"}, Count: 2},
+	}, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 44, result.Count)
+	assert.Equal(t, 12, result.Groups[0].Count)
+	assert.True(t, result.Groups[2].Suppressed)
+	assert.Equal(t, 0, result.Groups[2].Count)
+	assert.Equal(t, models.QueryPathMaterializedView, result.Metadata.Path)
+	mockResponseRepo.AssertNotCalled(t, "Query", ctx, query)
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// directIdentifierKeys are snapshot_core keys that identify an employee on their own.
+// New snapshots never contain them; older rows may.
+var directIdentifierKeys = []string{"employee_name", "employee_email"}
+
+// Permission is a capability granted to the caller of a service method
+type Permission string
+
+const (
+	// PermissionViewIdentifiers allows dashboard results to include raw employee identifiers
+	PermissionViewIdentifiers Permission = "responses:view_identifiers"
+	// PermissionEraseEmployees allows executing right-to-erasure requests
+	PermissionEraseEmployees Permission = "employees:erase"
+	// PermissionManageResponses allows voiding responses and reading their version history
+	PermissionManageResponses Permission = "responses:manage"
+	// PermissionManageSurveys allows creating, changing and deleting survey definitions
+	PermissionManageSurveys Permission = "surveys:manage"
+	// PermissionManageDashboards allows creating, changing and deleting saved dashboards
+	PermissionManageDashboards Permission = "dashboards:manage"
+)
+
+// ErrPermissionDenied is returned when the caller lacks a required permission
+var ErrPermissionDenied = errors.New("permission denied")
+
+type permissionsKey struct{}
+
+// WithPermissions returns a context carrying the caller's granted permissions
+func WithPermissions(ctx context.Context, perms ...Permission) context.Context {
+	return context.WithValue(ctx, permissionsKey{}, perms)
+}
+
+// HasPermission reports whether the caller in ctx was granted perm
+func HasPermission(ctx context.Context, perm Permission) bool {
+	perms, _ := ctx.Value(permissionsKey{}).([]Permission)
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePermissions parses a comma-separated permission list
+func ParsePermissions(raw string) []Permission {
+	var perms []Permission
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			perms = append(perms, Permission(p))
+		}
+	}
+	return perms
+}
+
+// Pseudonymizer derives stable per-tenant pseudonyms for employee IDs
+type Pseudonymizer struct {
+	masterKey []byte
+}
+
+func NewPseudonymizer(masterKey []byte) *Pseudonymizer {
+	return &Pseudonymizer{masterKey: masterKey}
+}
+
+// Pseudonymize returns HMAC-SHA256(tenantKey, employeeID), where tenantKey is
+// itself derived from the master key so pseudonyms never join across tenants
+func (p *Pseudonymizer) Pseudonymize(tenantID, employeeID string) string {
+	tenantMac := hmac.New(sha256.New, p.masterKey)
+	tenantMac.Write([]byte(tenantID))
+
+	mac := hmac.New(sha256.New, tenantMac.Sum(nil))
+	mac.Write([]byte(employeeID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// redactIdentifiers strips raw identifiers from responses unless the caller may see them
+func redactIdentifiers(ctx context.Context, result *models.DashboardResult) {
+	if HasPermission(ctx, PermissionViewIdentifiers) {
+		return
+	}
+
+	for i := range result.Responses {
+		redactResponse(&result.Responses[i])
+	}
+}
+
+// redactResponse strips raw identifiers from one response. Snapshot version IDs are
+// derived from the employee ID, so they are cleared too.
+func redactResponse(r *models.Response) {
+	r.EmployeeID = ""
+	r.VersionID = ""
+	for _, key := range directIdentifierKeys {
+		delete(r.SnapshotCore, key)
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"dashboard-case-study/pkg/repository"
+)
+
+const (
+	// DefaultRefreshCheckInterval is how often the scheduler checks whether a refresh is due
+	DefaultRefreshCheckInterval = 30 * time.Second
+	// DefaultRefreshMaxAge is the longest the views may go without a refresh
+	DefaultRefreshMaxAge = time.Hour
+	// DefaultRefreshBurst is the number of changed responses that triggers an early refresh
+	DefaultRefreshBurst = 100
+)
+
+// RefreshScheduler keeps the dashboard materialized views fresh. Every replica runs
+// one; only the holder of the leader lock refreshes, so refreshes never overlap.
+type RefreshScheduler struct {
+	refreshRepo repository.ViewRefreshRepository
+	leader      repository.LeaderLock
+	interval    time.Duration
+	maxAge      time.Duration
+	burst       int
+	now         func() time.Time
+}
+
+func NewRefreshScheduler(refreshRepo repository.ViewRefreshRepository, leader repository.LeaderLock) *RefreshScheduler {
+	return &RefreshScheduler{
+		refreshRepo: refreshRepo,
+		leader:      leader,
+		interval:    DefaultRefreshCheckInterval,
+		maxAge:      DefaultRefreshMaxAge,
+		burst:       DefaultRefreshBurst,
+		now:         time.Now,
+	}
+}
+
+// SetPolicy overrides when refreshes happen: at least every maxAge, and early once
+// burst responses to open surveys changed since the last refresh
+func (s *RefreshScheduler) SetPolicy(interval, maxAge time.Duration, burst int) {
+	s.interval = interval
+	s.maxAge = maxAge
+	s.burst = burst
+}
+
+// Run checks for due refreshes until ctx is cancelled, then gives up leadership
+func (s *RefreshScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Tick(ctx); err != nil {
+			log.Printf("view refresh: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			// The lock dies with its connection anyway; releasing lets a peer take over sooner
+			if err := s.leader.Release(context.Background()); err != nil {
+				log.Printf("view refresh: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs one check and reports whether the views were refreshed
+func (s *RefreshScheduler) Tick(ctx context.Context) (bool, error) {
+	leader, err := s.leader.TryAcquire(ctx)
+	if err != nil || !leader {
+		return false, err
+	}
+
+	due, err := s.due(ctx)
+	if err != nil || !due {
+		return false, err
+	}
+
+	if err := s.refreshRepo.Refresh(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// due reports whether the views are older than maxAge or a burst of changes arrived
+func (s *RefreshScheduler) due(ctx context.Context) (bool, error) {
+	last, err := s.refreshRepo.LastRefresh(ctx, repository.SummaryViewName)
+	if err != nil {
+		return false, err
+	}
+	if last == nil || s.now().Sub(last.DataAsOf) >= s.maxAge {
+		return true, nil
+	}
+
+	pending, err := s.refreshRepo.PendingChanges(ctx, last.DataAsOf)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for submission bursts: %w", err)
+	}
+	return pending >= s.burst, nil
+}
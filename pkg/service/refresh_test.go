@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockViewRefreshRepository is a mock implementation for testing
+type MockViewRefreshRepository struct {
+	mock.Mock
+}
+
+func (m *MockViewRefreshRepository) Refresh(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockViewRefreshRepository) LastRefresh(ctx context.Context, viewName string) (*models.ViewRefresh, error) {
+	args := m.Called(ctx, viewName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ViewRefresh), args.Error(1)
+}
+
+func (m *MockViewRefreshRepository) PendingChanges(ctx context.Context, since time.Time) (int, error) {
+	args := m.Called(ctx, since)
+	return args.Int(0), args.Error(1)
+}
+
+// MockLeaderLock is a mock implementation for testing
+type MockLeaderLock struct {
+	mock.Mock
+}
+
+func (m *MockLeaderLock) TryAcquire(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLeaderLock) Release(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// TestRefreshTick tests when the scheduler refreshes the views
+func TestRefreshTick(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		leader    bool
+		last      *models.ViewRefresh
+		pending   int
+		refreshed bool
+	}{
+		{name: "follower", leader: false, refreshed: false},
+		{name: "never refreshed", leader: true, refreshed: true},
+		{name: "stale", leader: true, last: &models.ViewRefresh{DataAsOf: now.Add(-2 * time.Hour)}, refreshed: true},
+		{name: "burst", leader: true, last: &models.ViewRefresh{DataAsOf: now.Add(-5 * time.Minute)}, pending: 150, refreshed: true},
+		{name: "quiet", leader: true, last: &models.ViewRefresh{DataAsOf: now.Add(-5 * time.Minute)}, pending: 3, refreshed: false},
+	}
+
+	for _, tt := range tests {
+		mockRefreshRepo := new(MockViewRefreshRepository)
+		mockLeader := new(MockLeaderLock)
+		scheduler := NewRefreshScheduler(mockRefreshRepo, mockLeader)
+		scheduler.now = func() time.Time { return now }
+
+		mockLeader.On("TryAcquire", ctx).Return(tt.leader, nil)
+		if tt.last != nil {
+			mockRefreshRepo.On("LastRefresh", ctx, repository.SummaryViewName).Return(tt.last, nil)
+			mockRefreshRepo.On("PendingChanges", ctx, tt.last.DataAsOf).Return(tt.pending, nil)
+		} else {
+			mockRefreshRepo.On("LastRefresh", ctx, repository.SummaryViewName).Return(nil, nil)
+		}
+		mockRefreshRepo.On("Refresh", ctx).Return(nil)
+
+		refreshed, err := scheduler.Tick(ctx)
+
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.refreshed, refreshed, tt.name)
+		if !tt.refreshed {
+			mockRefreshRepo.AssertNotCalled(t, "Refresh", ctx)
+		}
+	}
+}
+
+// TestSummaryReportsDataAsOf tests that view-served results carry the view's snapshot time
+func TestSummaryReportsDataAsOf(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockRefreshRepo := new(MockViewRefreshRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+	service.SetRefreshRepository(mockRefreshRepo)
+
+	ctx := context.Background()
+	asOf := time.Date(2024, 6, 1, 11, 0, 0, 0, time.UTC)
+	mockResponseRepo.On("AggregateSummary", ctx, mock.Anything).Return([]models.GroupResult{}, nil)
+	mockRefreshRepo.On("LastRefresh", ctx, repository.SummaryViewName).Return(&models.ViewRefresh{DataAsOf: asOf}, nil)
+
+	result, err := service.Query(ctx, summaryQuery())
+
+	assert.NoError(t, err)
+	assert.Equal(t, asOf, *result.Metadata.DataAsOf)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// RollupService maintains the response_rollups tables outside the write path
+type RollupService struct {
+	rollupRepo repository.RollupRepository
+}
+
+func NewRollupService(rollupRepo repository.RollupRepository) *RollupService {
+	return &RollupService{rollupRepo: rollupRepo}
+}
+
+// Rebuild recomputes the rollups from survey_responses and verifies the result.
+// An empty tenantID rebuilds every tenant.
+func (s *RollupService) Rebuild(ctx context.Context, tenantID string) (*models.RollupReport, error) {
+	n, err := s.rollupRepo.Rebuild(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.Verify(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	report.RowsRebuilt = n
+	return report, nil
+}
+
+// Verify reports every rollup row that disagrees with a recount of survey_responses
+func (s *RollupService) Verify(ctx context.Context, tenantID string) (*models.RollupReport, error) {
+	mismatches, err := s.rollupRepo.Verify(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RollupReport{Mismatches: mismatches}, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRollupRepository is a mock implementation for testing
+type MockRollupRepository struct {
+	mock.Mock
+}
+
+func (m *MockRollupRepository) Rebuild(ctx context.Context, tenantID string) (int, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRollupRepository) Verify(ctx context.Context, tenantID string) ([]models.RollupMismatch, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]models.RollupMismatch), args.Error(1)
+}
+
+// TestRollupRebuildVerifies tests that a rebuild is followed by a verification pass
+func TestRollupRebuildVerifies(t *testing.T) {
+	mockRollupRepo := new(MockRollupRepository)
+	service := NewRollupService(mockRollupRepo)
+
+	ctx := context.Background()
+	mockRollupRepo.On("Rebuild", ctx, "tenant_demo").Return(42, nil)
+	mockRollupRepo.On("Verify", ctx, "tenant_demo").Return([]models.RollupMismatch{}, nil)
+
+	report, err := service.Rebuild(ctx, "tenant_demo")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42, report.RowsRebuilt)
+	assert.Empty(t, report.Mismatches)
+	mockRollupRepo.AssertExpectations(t)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+// DefaultSchemaTTL is how long a tenant's snapshot schema is reused before it is read
+// again; reading it scans the tenant's responses
+const DefaultSchemaTTL = 10 * time.Minute
+
+// schemaCache holds each tenant's snapshot schema for a while
+type schemaCache struct {
+	repo repository.SnapshotSchemaRepository
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedSchema
+}
+
+type cachedSchema struct {
+	schema   models.SnapshotSchema
+	loadedAt time.Time
+}
+
+func newSchemaCache(repo repository.SnapshotSchemaRepository, ttl time.Duration) *schemaCache {
+	return &schemaCache{repo: repo, ttl: ttl, entries: make(map[string]cachedSchema)}
+}
+
+// get returns the tenant's schema, reading it when missing or older than the TTL
+func (c *schemaCache) get(ctx context.Context, tenantID string) (models.SnapshotSchema, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[tenantID]
+	c.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < c.ttl {
+		return entry.schema, nil
+	}
+
+	schema, err := c.repo.SnapshotSchema(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[tenantID] = cachedSchema{schema: schema, loadedAt: time.Now()}
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// validate checks a query as sent by the caller, including its filter keys against the
+// tenant's schema when a schema repository is configured
+func (s *DashboardService) validate(ctx context.Context, query models.DashboardQuery) error {
+	var schema models.SnapshotSchema
+	if s.schemas != nil {
+		var err error
+		if schema, err = s.schemas.get(ctx, query.TenantID); err != nil {
+			return err
+		}
+	}
+	return validateDashboardQuery(query, schema)
+}
@@ -2,7 +2,13 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"dashboard-case-study/pkg/models"
@@ -14,6 +20,9 @@ import (
 type SnapshotService struct {
 	employeeRepo repository.EmployeeRepository
 	orgRepo      repository.OrgRepository
+	bands        models.BandConfig
+	privacy      models.SnapshotPrivacyPolicy
+	pseudonyms   *Pseudonymizer
 }
 
 func NewSnapshotService(
@@ -23,13 +32,33 @@ func NewSnapshotService(
 	return &SnapshotService{
 		employeeRepo: employeeRepo,
 		orgRepo:      orgRepo,
+		bands:        DefaultBandConfig(),
+		privacy:      models.SnapshotPrivacyPolicy{Identifiers: models.IdentifierPolicyExclude},
 	}
 }
 
+// SetBandConfig overrides the demographic bands applied to future snapshots
+func (s *SnapshotService) SetBandConfig(bands models.BandConfig) {
+	s.bands = bands
+}
+
+// SetPrivacyPolicy controls how direct identifiers are captured. Pseudonymisation
+// requires a Pseudonymizer holding the tenant key material.
+func (s *SnapshotService) SetPrivacyPolicy(policy models.SnapshotPrivacyPolicy, pseudonyms *Pseudonymizer) {
+	s.privacy = policy
+	s.pseudonyms = pseudonyms
+}
+
 // CaptureSnapshot captures employee and org state at given timestamp
 func (s *SnapshotService) CaptureSnapshot(ctx context.Context, employeeID string, timestamp time.Time) (*models.Snapshot, error) {
 	// Get current employee state
 	employee, err := s.employeeRepo.GetByID(ctx, employeeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// The employee ID comes from the request, so this is a bad field, not a missing route
+		verr := &ValidationError{}
+		verr.Add("employee_id", "unknown", "no employee has this ID")
+		return nil, verr
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get employee: %w", err)
 	}
@@ -42,6 +71,9 @@ func (s *SnapshotService) CaptureSnapshot(ctx context.Context, employeeID string
 
 	// Build core snapshot (20 critical attributes)
 	snapshotCore := s.buildCoreSnapshot(employee, orgUnit, timestamp)
+	if err := s.applyPrivacyPolicy(snapshotCore, employee); err != nil {
+		return nil, err
+	}
 
 	// Generate version ID for this point in time
 	versionID := s.generateVersionID(employeeID, timestamp)
@@ -59,11 +91,11 @@ func (s *SnapshotService) buildCoreSnapshot(
 	orgUnit *models.OrgUnit,
 	timestamp time.Time,
 ) map[string]interface{} {
-	return map[string]interface{}{
-		// Employee identity
-		"employee_name":  employee.Name,
-		"employee_email": employee.Email,
+	age := calculateAge(employee.BirthDate, timestamp)
+	tenureMonths := completedMonths(employee.HireDate, timestamp)
 
+	// Direct identifiers (name, email) are never copied; see applyPrivacyPolicy
+	return map[string]interface{}{
 		// Organizational context
 		"department": orgUnit.UnitName,
 		"unit_id":    orgUnit.UnitID,
@@ -74,100 +106,340 @@ func (s *SnapshotService) buildCoreSnapshot(
 		"role":              employee.Role,
 
 		// Demographics (calculated at response time)
-		"age":    calculateAge(employee.BirthDate, timestamp),
-		"tenure": calculateTenure(employee.HireDate, timestamp),
+		"age":         age,
+		"tenure":      calculateTenure(employee.HireDate, timestamp),
+		"age_band":    bandFor(s.bands.AgeBands, float64(age)),
+		"tenure_band": bandFor(s.bands.TenureBands, float64(tenureMonths)/12),
 
 		// Metadata
-		"snapshot_version": "1.0",
+		"snapshot_version": "1.2",
 		"snapshot_time":    timestamp.Format(time.RFC3339),
 	}
 }
 
-func (s *SnapshotService) generateVersionID(employeeID string, timestamp time.Time) string {
-	return fmt.Sprintf("%s_%d", employeeID, timestamp.Unix())
-}
-
-// Helper functions
-func calculateAge(birthDate, asOf time.Time) int {
-	age := asOf.Year() - birthDate.Year()
-	if asOf.YearDay() < birthDate.YearDay() {
-		age--
+// applyPrivacyPolicy adds the identifier representation allowed by the policy
+func (s *SnapshotService) applyPrivacyPolicy(snapshotCore map[string]interface{}, employee *models.Employee) error {
+	switch s.privacy.Identifiers {
+	case models.IdentifierPolicyExclude:
+		return nil
+	case models.IdentifierPolicyPseudonymise:
+		if s.pseudonyms == nil {
+			return fmt.Errorf("pseudonymisation policy requires a pseudonymizer")
+		}
+		snapshotCore["employee_pseudonym"] = s.pseudonyms.Pseudonymize(employee.TenantID, employee.EmployeeID)
+		return nil
+	default:
+		return fmt.Errorf("invalid identifier policy: %s", s.privacy.Identifiers)
 	}
-	return age
 }
 
-func calculateTenure(hireDate, asOf time.Time) float64 {
-	years := asOf.Sub(hireDate).Hours() / (24 * 365.25)
-	return float64(int(years*10)) / 10 // Round to 1 decimal
+func (s *SnapshotService) generateVersionID(employeeID string, timestamp time.Time) string {
+	return fmt.Sprintf("%s_%d", employeeID, timestamp.Unix())
 }
 
+// DefaultAnonymityThreshold is the minimum group size shown on dashboards
+const DefaultAnonymityThreshold = 5
+
 // DashboardService handles dashboard queries
 type DashboardService struct {
-	responseRepo repository.ResponseRepository
-	orgRepo      repository.OrgRepository
-	orgMapper    *OrgMapper
+	responseRepo       repository.ResponseRepository
+	orgRepo            repository.OrgRepository
+	surveyRepo         repository.SurveyRepository
+	orgMapper          *OrgMapper
+	anonymityThreshold int
+	refreshRepo        repository.ViewRefreshRepository // Optional; reports view freshness
+	archive            repository.ArchiveStore          // Optional; cold storage for old months
+	schemas            *schemaCache                     // Optional; checks filter keys per tenant
 }
 
 func NewDashboardService(
 	responseRepo repository.ResponseRepository,
 	orgRepo repository.OrgRepository,
+	surveyRepo repository.SurveyRepository,
 ) *DashboardService {
 	return &DashboardService{
-		responseRepo: responseRepo,
-		orgRepo:      orgRepo,
-		orgMapper:    NewOrgMapper(orgRepo),
+		responseRepo:       responseRepo,
+		orgRepo:            orgRepo,
+		surveyRepo:         surveyRepo,
+		orgMapper:          NewOrgMapper(orgRepo),
+		anonymityThreshold: DefaultAnonymityThreshold,
 	}
 }
 
-// Query executes a dashboard query with filter mode support
+// SetAnonymityThreshold overrides the minimum group size shown on dashboards
+func (s *DashboardService) SetAnonymityThreshold(n int) {
+	s.anonymityThreshold = n
+}
+
+// SetRefreshRepository enables "data as of" timestamps on results served from views
+func (s *DashboardService) SetRefreshRepository(refreshRepo repository.ViewRefreshRepository) {
+	s.refreshRepo = refreshRepo
+}
+
+// SetArchiveStore lets queries whose time range reaches archived months read them
+// from cold storage
+func (s *DashboardService) SetArchiveStore(archive repository.ArchiveStore) {
+	s.archive = archive
+}
+
+// SetSchemaRepository makes queries check filter and group-by keys against the keys
+// the tenant's snapshots actually carry
+func (s *DashboardService) SetSchemaRepository(schemaRepo repository.SnapshotSchemaRepository) {
+	s.schemas = newSchemaCache(schemaRepo, DefaultSchemaTTL)
+}
+
+// Query executes a dashboard query with filter mode support.
+// Raw employee identifiers are only returned to callers with PermissionViewIdentifiers.
 func (s *DashboardService) Query(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
+	if err := s.validate(ctx, query); err != nil {
+		return nil, err
+	}
+	if query.Explain {
+		return s.explain(ctx, query)
+	}
+
+	if err := s.loadArchived(ctx, &query); err != nil {
+		return nil, err
+	}
+
+	var result *models.DashboardResult
+	var err error
+
 	switch query.FilterMode {
 	case models.FilterModeHistorical:
-		return s.queryHistorical(ctx, query)
+		result, err = s.queryHistorical(ctx, query)
 	case models.FilterModeCurrent:
-		return s.queryCurrent(ctx, query)
+		result, err = s.queryCurrent(ctx, query)
 	case models.FilterModeHybrid:
-		return s.queryHybrid(ctx, query)
+		result, err = s.queryHybrid(ctx, query)
 	default:
-		return nil, fmt.Errorf("invalid filter mode: %s", query.FilterMode)
+		return nil, fmt.Errorf("%w: invalid filter mode: %s", ErrInvalidRequest, query.FilterMode)
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	redactIdentifiers(ctx, result)
+	return result, nil
 }
 
 func (s *DashboardService) queryHistorical(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
 	// Direct query on snapshot_core
+	return s.execute(ctx, query)
+}
+
+// execute runs the (already translated) query and its optional group-by aggregation
+func (s *DashboardService) execute(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
+	path, reason := planQuery(query)
+	if path == models.QueryPathMaterializedView {
+		return s.executeSummary(ctx, query)
+	}
+
+	// Parent units are resolved in today's hierarchy, so group by current units
+	relabel := false
+	if query.UnitMapping == nil && containsBenchmark(query.Benchmarks, models.BenchmarkParentUnit) {
+		unitMapping, err := s.currentUnitMapping(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		query.UnitMapping = unitMapping
+		relabel = true
+	}
+
 	responses, err := s.responseRepo.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.DashboardResult{
+	result := &models.DashboardResult{
 		Responses: responses,
 		Count:     len(responses),
-	}, nil
+		Metadata:  &models.QueryMetadata{Path: path, Reason: reason, ArchivedResponses: len(query.Archived)},
+	}
+
+	if len(query.GroupBy) > 0 || len(query.Metrics) > 0 || len(query.Benchmarks) > 0 {
+		groups, err := s.responseRepo.Aggregate(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		s.finalizeMetrics(groups)
+		result.Groups = groups
+	}
+	if relabel {
+		s.labelCurrentDepartments(ctx, result.Groups)
+	}
+
+	return result, nil
 }
 
 func (s *DashboardService) queryCurrent(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
-	// Translate current org structure to historical unit IDs
-	if dept, ok := query.Filters["department"].(string); ok {
-		historicalUnitIDs, err := s.orgMapper.MapCurrentToHistorical(ctx, dept)
+	if err := s.mapCurrentFilters(ctx, &query); err != nil {
+		return nil, err
+	}
+
+	// Org group-bys use today's structure, so every bucket groups the same way
+	if !groupsByOrg(query.GroupBy) {
+		return s.execute(ctx, query)
+	}
+
+	unitMapping, err := s.currentUnitMapping(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	query.UnitMapping = unitMapping
+
+	result, err := s.execute(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.labelCurrentDepartments(ctx, result.Groups)
+	return result, nil
+}
+
+// MaxArchivedResponses caps the archived rows one query may restore from cold storage
+const MaxArchivedResponses = 50000
+
+// loadArchived attaches the tenant's archived responses inside the time range, scanned
+// from every archived month the range touches. Rows outside the range, of another
+// survey or (unless requested) inactive are skipped while scanning; SQL applies the
+// remaining filters. Queries outside archived months are untouched.
+func (s *DashboardService) loadArchived(ctx context.Context, query *models.DashboardQuery) error {
+	if s.archive == nil || query.Archived != nil {
+		return nil
+	}
+
+	months, err := s.archive.Months(ctx, query.TenantID)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.ArchiveFilter{
+		From:       query.TimeRange.From,
+		To:         query.TimeRange.To,
+		SurveyID:   query.SurveyID,
+		ActiveOnly: !query.IncludeWithdrawn,
+	}
+	archived := []models.Response{}
+	for _, month := range months {
+		if month.After(query.TimeRange.To) || !month.AddDate(0, 1, 0).After(query.TimeRange.From) {
+			continue
+		}
+		responses, err := s.archive.Scan(ctx, query.TenantID, month, filter, MaxArchivedResponses-len(archived))
 		if err != nil {
-			return nil, fmt.Errorf("failed to map current to historical: %w", err)
+			return err
 		}
+		archived = append(archived, responses...)
+		if len(archived) > MaxArchivedResponses {
+			return fmt.Errorf("%w: more than %d archived responses in the time range; narrow it or filter by survey",
+				ErrInvalidRequest, MaxArchivedResponses)
+		}
+	}
+	query.Archived = archived
+	return nil
+}
 
-		// Replace department filter with unit_id IN clause
-		delete(query.Filters, "department")
-		query.Filters["unit_id"] = historicalUnitIDs
+// rowQuery prepares a query that selects individual responses rather than groups.
+// CURRENT filters are mapped to historical units; HYBRID selects the historical set.
+func (s *DashboardService) rowQuery(ctx context.Context, query models.DashboardQuery) (models.DashboardQuery, error) {
+	if err := s.validate(ctx, query); err != nil {
+		return query, err
 	}
 
-	responses, err := s.responseRepo.Query(ctx, query)
+	switch query.FilterMode {
+	case models.FilterModeCurrent:
+		if err := s.mapCurrentFilters(ctx, &query); err != nil {
+			return query, err
+		}
+	case models.FilterModeHistorical, models.FilterModeHybrid:
+	default:
+		return query, fmt.Errorf("%w: invalid filter mode: %s", ErrInvalidRequest, query.FilterMode)
+	}
+
+	if err := s.loadArchived(ctx, &query); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// mapCurrentFilters translates a current department filter to historical unit IDs
+func (s *DashboardService) mapCurrentFilters(ctx context.Context, query *models.DashboardQuery) error {
+	dept, ok := query.Filters["department"].(string)
+	if !ok {
+		return nil
+	}
+
+	historicalUnitIDs, err := s.orgMapper.MapCurrentToHistorical(ctx, query.TenantID, dept)
+	if err != nil {
+		return fmt.Errorf("failed to map current to historical: %w", err)
+	}
+
+	// Replace department filter with unit_id IN clause
+	filters := make(map[string]interface{}, len(query.Filters))
+	for k, v := range query.Filters {
+		if k != "department" {
+			filters[k] = v
+		}
+	}
+	filters["unit_id"] = historicalUnitIDs
+	query.Filters = filters
+	return nil
+}
+
+// groupsByOrg reports whether any group-by dimension is an org unit attribute
+func groupsByOrg(groupBy []string) bool {
+	for _, field := range groupBy {
+		if field == "department" || field == "unit_id" {
+			return true
+		}
+	}
+	return false
+}
+
+func containsBenchmark(scopes []models.BenchmarkScope, scope models.BenchmarkScope) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// currentUnitMapping maps every historical unit in the filtered set to its current unit
+func (s *DashboardService) currentUnitMapping(ctx context.Context, query models.DashboardQuery) (map[string]string, error) {
+	unitIDs, err := s.responseRepo.DistinctValues(ctx, query, "unit_id")
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.DashboardResult{
-		Responses: responses,
-		Count:     len(responses),
-	}, nil
+	mapping := make(map[string]string, len(unitIDs))
+	for _, unitID := range unitIDs {
+		current, _, err := s.orgMapper.MapHistoricalToCurrent(ctx, unitID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map unit %s: %w", unitID, err)
+		}
+		mapping[unitID] = current
+	}
+	return mapping, nil
+}
+
+// labelCurrentDepartments replaces current unit IDs in department keys with current names
+func (s *DashboardService) labelCurrentDepartments(ctx context.Context, groups []models.GroupResult) {
+	names := make(map[string]string)
+	for _, g := range groups {
+		unitID, ok := g.Key["department"]
+		if !ok {
+			continue
+		}
+		name, cached := names[unitID]
+		if !cached {
+			name = unitID
+			if unit, err := s.orgRepo.GetUnitByID(ctx, unitID); err == nil && unit != nil {
+				name = unit.UnitName
+			}
+			names[unitID] = name
+		}
+		g.Key["department"] = name
+	}
 }
 
 func (s *DashboardService) queryHybrid(ctx context.Context, query models.DashboardQuery) (*models.DashboardResult, error) {
@@ -206,9 +478,12 @@ func (s *DashboardService) mergeResults(historical, current *models.DashboardRes
 		}
 	}
 
+	// Group keys are snapshot attributes, so the historical breakdown applies
 	return &models.DashboardResult{
 		Responses: merged,
 		Count:     len(merged),
+		Groups:    historical.Groups,
+		Metadata:  historical.Metadata,
 		Provenance: &models.ProvenanceInfo{
 			HistoricalCount: historical.Count,
 			CurrentCount:    current.Count,
@@ -219,55 +494,207 @@ func (s *DashboardService) mergeResults(historical, current *models.DashboardRes
 // OrgMapper handles organizational unit mapping
 type OrgMapper struct {
 	orgRepo repository.OrgRepository
-	cache   map[string][]string // Cache of current → historical mappings
+
+	mu    sync.Mutex                       // Guards cache; saved dashboards map widgets concurrently
+	cache map[string][]models.MappingChain // Cache of current → historical mappings by tenant and name
 }
 
 func NewOrgMapper(orgRepo repository.OrgRepository) *OrgMapper {
 	return &OrgMapper{
 		orgRepo: orgRepo,
-		cache:   make(map[string][]string),
+		cache:   make(map[string][]models.MappingChain),
 	}
 }
 
 // MapCurrentToHistorical maps current unit name to all historical unit IDs
-func (m *OrgMapper) MapCurrentToHistorical(ctx context.Context, currentUnitName string) ([]string, error) {
-	// Check cache
-	if cached, ok := m.cache[currentUnitName]; ok {
+func (m *OrgMapper) MapCurrentToHistorical(ctx context.Context, tenantID, currentUnitName string) ([]string, error) {
+	chains, err := m.TraceCurrentToHistorical(ctx, tenantID, currentUnitName)
+	if err != nil {
+		return nil, err
+	}
+
+	unitIDs := make([]string, len(chains))
+	for i, chain := range chains {
+		unitIDs[i] = chain.HistoricalUnitID
+	}
+	return unitIDs, nil
+}
+
+// TraceCurrentToHistorical walks restructure mappings backwards from today's units
+// named currentUnitName and returns, for each historical unit found, the forward chain
+// that leads to one of them. A unit is only included when that chain is attributable,
+// so the result agrees with MapHistoricalToCurrent: members of a SPLIT unit are left
+// out. No chains are returned when no current unit has the name.
+func (m *OrgMapper) TraceCurrentToHistorical(ctx context.Context, tenantID, currentUnitName string) ([]models.MappingChain, error) {
+	key := tenantID + "/" + currentUnitName
+	m.mu.Lock()
+	cached, ok := m.cache[key]
+	m.mu.Unlock()
+	if ok {
 		return cached, nil
 	}
 
-	// Find current unit by name
-	// In production, this would query org_units_history WHERE unit_name = X AND valid_to IS NULL
-	// For POC, we'll use a simplified approach
+	units, err := m.orgRepo.FindCurrentUnitsByName(ctx, currentUnitName)
+	if err != nil {
+		return nil, err
+	}
 
-	// TODO: Implement backward graph traversal
-	// For now, return single unit
-	result := []string{currentUnitName}
+	current := make(map[string]bool, len(units))
+	seen := make(map[string]bool)
+	var frontier []string
+	for _, unit := range units {
+		current[unit.UnitID] = true
+		if !seen[unit.UnitID] {
+			seen[unit.UnitID] = true
+			frontier = append(frontier, unit.UnitID)
+		}
+	}
 
-	// Cache result
-	m.cache[currentUnitName] = result
+	// Breadth-first over mappings that name a visited unit as a target
+	for depth := 0; depth < maxMappingDepth && len(frontier) > 0; depth++ {
+		var next []string
+		for _, unitID := range frontier {
+			mappings, err := m.orgRepo.FindMappingsByTarget(ctx, unitID)
+			if err != nil {
+				return nil, err
+			}
+			for _, mapping := range mappings {
+				if !seen[mapping.SourceUnitID] {
+					seen[mapping.SourceUnitID] = true
+					next = append(next, mapping.SourceUnitID)
+				}
+			}
+		}
+		frontier = next
+	}
 
-	return result, nil
+	candidates := make([]string, 0, len(seen))
+	for unitID := range seen {
+		candidates = append(candidates, unitID)
+	}
+	sort.Strings(candidates)
+
+	// A source may have been remapped again since, so keep only units that lead here
+	chains := []models.MappingChain{}
+	for _, unitID := range candidates {
+		chain, err := m.TraceHistoricalToCurrent(ctx, unitID)
+		if err != nil {
+			return nil, err
+		}
+		if chain.Attributable && current[chain.CurrentUnitID] {
+			chains = append(chains, *chain)
+		}
+	}
+
+	m.mu.Lock()
+	m.cache[key] = chains
+	m.mu.Unlock()
+	return chains, nil
 }
 
+// maxMappingDepth bounds forward mapping chains to guard against cycles
+const maxMappingDepth = 10
+
+// MapHistoricalToCurrent follows restructure mappings forward from a historical unit
+// to the unit that represents it today. It returns false when the chain hits a SPLIT,
+// since members of a split unit cannot be attributed to a single successor.
+func (m *OrgMapper) MapHistoricalToCurrent(ctx context.Context, unitID string) (string, bool, error) {
+	chain, err := m.TraceHistoricalToCurrent(ctx, unitID)
+	if err != nil {
+		return "", false, err
+	}
+	return chain.CurrentUnitID, chain.Attributable, nil
+}
+
+// TraceHistoricalToCurrent is MapHistoricalToCurrent, also returning each restructure
+// mapping followed on the way
+func (m *OrgMapper) TraceHistoricalToCurrent(ctx context.Context, unitID string) (*models.MappingChain, error) {
+	chain := &models.MappingChain{HistoricalUnitID: unitID, Steps: []models.OrgUnitMapping{}, Attributable: true}
+	current := unitID
+	for i := 0; i < maxMappingDepth; i++ {
+		mapping, err := m.orgRepo.GetMapping(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		if mapping == nil || len(mapping.TargetUnitIDs) == 0 {
+			break
+		}
+		chain.Steps = append(chain.Steps, *mapping)
+		if mapping.RelationshipType == models.MappingTypeSplit {
+			chain.Attributable = false
+			break
+		}
+
+		// RENAME keeps the unit ID, so a self-mapping ends the chain
+		next := mapping.TargetUnitIDs[0]
+		if next == current {
+			break
+		}
+		current = next
+	}
+	chain.CurrentUnitID = current
+	return chain, nil
+}
+
+// ErrIdempotencyKeyReused is returned when a key is replayed for a different submission
+var ErrIdempotencyKeyReused = repository.NewKindError(repository.ErrConflict, "idempotency key reused for a different submission")
+
 // ResponseService handles response submission
 type ResponseService struct {
 	responseRepo repository.ResponseRepository
+	surveyRepo   repository.SurveyRepository
 	snapshotSvc  *SnapshotService
 }
 
 func NewResponseService(
 	responseRepo repository.ResponseRepository,
+	surveyRepo repository.SurveyRepository,
 	snapshotSvc *SnapshotService,
 ) *ResponseService {
 	return &ResponseService{
 		responseRepo: responseRepo,
+		surveyRepo:   surveyRepo,
 		snapshotSvc:  snapshotSvc,
 	}
 }
 
-// Submit creates a new response with snapshot
-func (s *ResponseService) Submit(ctx context.Context, surveyID, employeeID, tenantID string, answers map[string]interface{}) (*models.Response, error) {
+// Submit creates a new response with snapshot. When idempotencyKey is set, a retry
+// with the same key returns the originally stored response instead of a new one.
+func (s *ResponseService) Submit(ctx context.Context, surveyID, employeeID, tenantID string, answers map[string]interface{}, idempotencyKey string) (*models.Response, error) {
+	if err := validateSubmission(employeeID, answers); err != nil {
+		return nil, err
+	}
+
+	hash, err := requestHash(surveyID, employeeID, answers)
+	if err != nil {
+		return nil, err
+	}
+	if idempotencyKey != "" {
+		existing, err := s.replay(ctx, surveyID, employeeID, tenantID, idempotencyKey, hash)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
+
+	survey, err := s.surveyRepo.GetByID(ctx, tenantID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey == nil {
+		return nil, fmt.Errorf("survey %w: %s", repository.ErrNotFound, surveyID)
+	}
+	if err := acceptsResponses(ctx, s.surveyRepo, survey, employeeID, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := validateAnswers(survey, answers); err != nil {
+		return nil, err
+	}
+
+	answersJSON, err := json.Marshal(answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal answers: %w", err)
+	}
+
 	// Capture snapshot at submission time
 	snapshot, err := s.snapshotSvc.CaptureSnapshot(ctx, employeeID, time.Now())
 	if err != nil {
@@ -276,19 +703,145 @@ func (s *ResponseService) Submit(ctx context.Context, surveyID, employeeID, tena
 
 	// Create response
 	response := &models.Response{
-		ResponseID:   repository.GenerateID(),
-		SurveyID:     surveyID,
-		EmployeeID:   employeeID,
-		SnapshotCore: snapshot.SnapshotCore,
-		VersionID:    snapshot.VersionID,
-		TenantID:     tenantID,
+		ResponseID:     repository.GenerateID(),
+		SurveyID:       surveyID,
+		EmployeeID:     employeeID,
+		SnapshotCore:   snapshot.SnapshotCore,
+		VersionID:      snapshot.VersionID,
+		Answers:        answersJSON,
+		TenantID:       tenantID,
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    hash,
+		OnePerEmployee: survey.SingleResponse,
 	}
 
 	// Store in database
 	err = s.responseRepo.Create(ctx, response)
+	if errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		// A concurrent retry won the race; return its response
+		return s.replay(ctx, surveyID, employeeID, tenantID, idempotencyKey, hash)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create response: %w", err)
 	}
 
 	return response, nil
 }
+
+// Update amends the answers of the respondent's own active response. Prior answers are
+// kept as versions; the snapshot is only recaptured if the survey opts in.
+func (s *ResponseService) Update(ctx context.Context, tenantID, responseID, employeeID string, answers map[string]interface{}) (*models.Response, error) {
+	if err := validateSubmission(employeeID, answers); err != nil {
+		return nil, err
+	}
+
+	response, err := s.getOwned(ctx, tenantID, responseID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	survey, err := s.surveyRepo.GetByID(ctx, tenantID, response.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey == nil {
+		return nil, fmt.Errorf("survey %w: %s", repository.ErrNotFound, response.SurveyID)
+	}
+	// Amendments are only accepted while the survey is open
+	if err := acceptsResponses(ctx, s.surveyRepo, survey, employeeID, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := validateAnswers(survey, answers); err != nil {
+		return nil, err
+	}
+
+	response.Answers, err = json.Marshal(answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal answers: %w", err)
+	}
+
+	if survey.ResnapshotOnEdit {
+		snapshot, err := s.snapshotSvc.CaptureSnapshot(ctx, employeeID, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture snapshot: %w", err)
+		}
+		response.SnapshotCore = snapshot.SnapshotCore
+		response.VersionID = snapshot.VersionID
+	}
+
+	if err := s.responseRepo.Update(ctx, response, employeeID); err != nil {
+		return nil, fmt.Errorf("failed to update response: %w", err)
+	}
+
+	return response, nil
+}
+
+// Withdraw retracts the respondent's own response from all dashboards
+func (s *ResponseService) Withdraw(ctx context.Context, tenantID, responseID, employeeID, reason string) error {
+	if _, err := s.getOwned(ctx, tenantID, responseID, employeeID); err != nil {
+		return err
+	}
+	return s.responseRepo.SetStatus(ctx, tenantID, responseID, models.ResponseStatusWithdrawn, employeeID, reason)
+}
+
+// Void invalidates a response (e.g. fraudulent) on behalf of an admin
+func (s *ResponseService) Void(ctx context.Context, tenantID, responseID, actorID, reason string) error {
+	if !HasPermission(ctx, PermissionManageResponses) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageResponses)
+	}
+	return s.responseRepo.SetStatus(ctx, tenantID, responseID, models.ResponseStatusVoided, actorID, reason)
+}
+
+// GetVersions returns the archived revisions of a response for audit
+func (s *ResponseService) GetVersions(ctx context.Context, tenantID, responseID string) ([]models.ResponseVersion, error) {
+	if !HasPermission(ctx, PermissionManageResponses) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageResponses)
+	}
+	return s.responseRepo.GetVersions(ctx, tenantID, responseID)
+}
+
+// getOwned loads a response and checks it belongs to employeeID within the tenant
+func (s *ResponseService) getOwned(ctx context.Context, tenantID, responseID, employeeID string) (*models.Response, error) {
+	response, err := s.responseRepo.GetByID(ctx, responseID)
+	if err != nil {
+		return nil, err
+	}
+	if response.TenantID != tenantID {
+		return nil, fmt.Errorf("response %w: %s", repository.ErrNotFound, responseID)
+	}
+	if response.EmployeeID != employeeID {
+		return nil, fmt.Errorf("%w: only the respondent can change a response", ErrPermissionDenied)
+	}
+	return response, nil
+}
+
+// replay returns the response previously stored under idempotencyKey, or nil if none.
+// The key must have been used for the same submission: same survey, employee and
+// answers. Keys stored without a hash only had survey and employee recorded.
+func (s *ResponseService) replay(ctx context.Context, surveyID, employeeID, tenantID, idempotencyKey, hash string) (*models.Response, error) {
+	existing, err := s.responseRepo.GetByIdempotencyKey(ctx, tenantID, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	if existing.SurveyID != surveyID || existing.EmployeeID != employeeID {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.RequestHash != "" && existing.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return existing, nil
+}
+
+// requestHash fingerprints a submission. Answers are encoded as JSON, which sorts map
+// keys, so equal answers always hash the same.
+func requestHash(surveyID, employeeID string, answers map[string]interface{}) (string, error) {
+	answersJSON, err := json.Marshal(answers)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal answers: %w", err)
+	}
+	sum := sha256.Sum256([]byte(surveyID + "\x00" + employeeID + "\x00" + string(answersJSON)))
+	return hex.EncodeToString(sum[:]), nil
+}
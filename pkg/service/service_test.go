@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -29,6 +31,184 @@ func (m *MockEmployeeRepository) GetHistory(ctx context.Context, employeeID stri
 	return args.Get(0).([]models.EmployeeHistory), args.Error(1)
 }
 
+// testTimeRange is the first quarter of 2024, for queries whose range does not matter
+var testTimeRange = models.TimeRange{
+	From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	To:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+}
+
+// MockResponseRepository is a mock implementation for testing
+type MockResponseRepository struct {
+	mock.Mock
+}
+
+func (m *MockResponseRepository) Create(ctx context.Context, response *models.Response) error {
+	args := m.Called(ctx, response)
+	return args.Error(0)
+}
+
+func (m *MockResponseRepository) GetByID(ctx context.Context, responseID string) (*models.Response, error) {
+	args := m.Called(ctx, responseID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Response), args.Error(1)
+}
+
+func (m *MockResponseRepository) GetByIdempotencyKey(ctx context.Context, tenantID, key string) (*models.Response, error) {
+	args := m.Called(ctx, tenantID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Response), args.Error(1)
+}
+
+func (m *MockResponseRepository) Update(ctx context.Context, response *models.Response, changedBy string) error {
+	args := m.Called(ctx, response, changedBy)
+	return args.Error(0)
+}
+
+func (m *MockResponseRepository) SetStatus(ctx context.Context, tenantID, responseID string, status models.ResponseStatus, changedBy, reason string) error {
+	args := m.Called(ctx, tenantID, responseID, status, changedBy, reason)
+	return args.Error(0)
+}
+
+func (m *MockResponseRepository) GetVersions(ctx context.Context, tenantID, responseID string) ([]models.ResponseVersion, error) {
+	args := m.Called(ctx, tenantID, responseID)
+	return args.Get(0).([]models.ResponseVersion), args.Error(1)
+}
+
+func (m *MockResponseRepository) Query(ctx context.Context, query models.DashboardQuery) ([]models.Response, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.Response), args.Error(1)
+}
+
+func (m *MockResponseRepository) Iterate(ctx context.Context, query models.DashboardQuery) (repository.ResponseIterator, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return &sliceResponseIterator{responses: args.Get(0).([]models.Response), err: args.Error(1)}, nil
+}
+
+// sliceResponseIterator iterates mocked responses, then reports err
+type sliceResponseIterator struct {
+	responses []models.Response
+	next      int
+	err       error
+}
+
+func (it *sliceResponseIterator) Next() bool {
+	if it.next >= len(it.responses) {
+		return false
+	}
+	it.next++
+	return true
+}
+
+func (it *sliceResponseIterator) Response() *models.Response {
+	return &it.responses[it.next-1]
+}
+
+func (it *sliceResponseIterator) Err() error {
+	return it.err
+}
+
+func (it *sliceResponseIterator) Close() error {
+	return nil
+}
+
+func (m *MockResponseRepository) Aggregate(ctx context.Context, query models.DashboardQuery) ([]models.GroupResult, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.GroupResult), args.Error(1)
+}
+
+func (m *MockResponseRepository) Comments(ctx context.Context, query models.DashboardQuery, questionID, search string, limit int) (int, []string, error) {
+	args := m.Called(ctx, query, questionID, search, limit)
+	return args.Int(0), args.Get(1).([]string), args.Error(2)
+}
+
+func (m *MockResponseRepository) AggregateSummary(ctx context.Context, query models.DashboardQuery) ([]models.GroupResult, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.GroupResult), args.Error(1)
+}
+
+func (m *MockResponseRepository) DistinctValues(ctx context.Context, query models.DashboardQuery, field string) ([]string, error) {
+	args := m.Called(ctx, query, field)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockResponseRepository) Explain(ctx context.Context, query models.DashboardQuery, path models.QueryPath) ([]models.ExplainedStatement, error) {
+	args := m.Called(ctx, query, path)
+	return args.Get(0).([]models.ExplainedStatement), args.Error(1)
+}
+
+func (m *MockResponseRepository) SnapshotKeys(ctx context.Context, query models.DashboardQuery) ([]string, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// MockSurveyRepository is a mock implementation for testing
+type MockSurveyRepository struct {
+	mock.Mock
+}
+
+func (m *MockSurveyRepository) Create(ctx context.Context, survey *models.Survey) error {
+	args := m.Called(ctx, survey)
+	return args.Error(0)
+}
+
+func (m *MockSurveyRepository) Update(ctx context.Context, survey *models.Survey) error {
+	args := m.Called(ctx, survey)
+	return args.Error(0)
+}
+
+func (m *MockSurveyRepository) Delete(ctx context.Context, tenantID, surveyID string) error {
+	args := m.Called(ctx, tenantID, surveyID)
+	return args.Error(0)
+}
+
+func (m *MockSurveyRepository) List(ctx context.Context, tenantID string) ([]models.Survey, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).([]models.Survey), args.Error(1)
+}
+
+func (m *MockSurveyRepository) Launch(ctx context.Context, survey *models.Survey) (int, error) {
+	args := m.Called(ctx, survey)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSurveyRepository) SetStatus(ctx context.Context, tenantID, surveyID string, from, to models.SurveyStatus) error {
+	args := m.Called(ctx, tenantID, surveyID, from, to)
+	return args.Error(0)
+}
+
+func (m *MockSurveyRepository) IsEligible(ctx context.Context, tenantID, surveyID, employeeID string) (bool, error) {
+	args := m.Called(ctx, tenantID, surveyID, employeeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSurveyRepository) GetParticipation(ctx context.Context, tenantID, surveyID string) (*models.Participation, error) {
+	args := m.Called(ctx, tenantID, surveyID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Participation), args.Error(1)
+}
+
+func (m *MockSurveyRepository) GetParticipationByUnit(ctx context.Context, tenantID, surveyID string) ([]models.UnitResponseCount, error) {
+	args := m.Called(ctx, tenantID, surveyID)
+	return args.Get(0).([]models.UnitResponseCount), args.Error(1)
+}
+
+func (m *MockSurveyRepository) GetByID(ctx context.Context, tenantID, surveyID string) (*models.Survey, error) {
+	args := m.Called(ctx, tenantID, surveyID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Survey), args.Error(1)
+}
+
 // MockOrgRepository is a mock implementation for testing
 type MockOrgRepository struct {
 	mock.Mock
@@ -63,6 +243,11 @@ func (m *MockOrgRepository) FindMappingsByTarget(ctx context.Context, targetUnit
 	return args.Get(0).([]models.OrgUnitMapping), args.Error(1)
 }
 
+func (m *MockOrgRepository) FindCurrentUnitsByName(ctx context.Context, unitName string) ([]models.OrgUnit, error) {
+	args := m.Called(ctx, unitName)
+	return args.Get(0).([]models.OrgUnit), args.Error(1)
+}
+
 // TestSnapshotCapture tests the snapshot capture functionality
 func TestSnapshotCapture(t *testing.T) {
 	// Setup
@@ -104,17 +289,201 @@ func TestSnapshotCapture(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, snapshot)
 	assert.Equal(t, employeeID, snapshot.EmployeeID)
-	assert.Equal(t, "John Doe", snapshot.SnapshotCore["employee_name"])
+	assert.NotContains(t, snapshot.SnapshotCore, "employee_name")
+	assert.NotContains(t, snapshot.SnapshotCore, "employee_email")
 	assert.Equal(t, "Sales APAC", snapshot.SnapshotCore["department"])
 	assert.Equal(t, "A", snapshot.SnapshotCore["performance_grade"])
 	assert.Equal(t, 35, snapshot.SnapshotCore["age"])            // Age at timestamp
 	assert.InDelta(t, 4.8, snapshot.SnapshotCore["tenure"], 0.2) // Tenure at timestamp
+	assert.Equal(t, "35-44", snapshot.SnapshotCore["age_band"])
+	assert.Equal(t, "3-5y", snapshot.SnapshotCore["tenure_band"])
 
 	// Verify mocks
 	mockEmployeeRepo.AssertExpectations(t)
 	mockOrgRepo.AssertExpectations(t)
 }
 
+// TestSnapshotUnknownEmployee tests that an unknown employee ID is reported as an
+// invalid field rather than a missing resource
+func TestSnapshotUnknownEmployee(t *testing.T) {
+	mockEmployeeRepo := new(MockEmployeeRepository)
+	service := NewSnapshotService(mockEmployeeRepo, new(MockOrgRepository))
+
+	ctx := context.Background()
+	mockEmployeeRepo.On("GetByID", ctx, "emp_x").Return(nil, fmt.Errorf("employee %w: emp_x", repository.ErrNotFound))
+
+	_, err := service.CaptureSnapshot(ctx, "emp_x", time.Now())
+
+	var verr *ValidationError
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, "employee_id", verr.Errors[0].Field)
+	assert.NotErrorIs(t, err, repository.ErrNotFound)
+}
+
+// TestSnapshotPseudonymisation tests that identifiers are replaced by a per-tenant HMAC
+func TestSnapshotPseudonymisation(t *testing.T) {
+	mockEmployeeRepo := new(MockEmployeeRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	service := NewSnapshotService(mockEmployeeRepo, mockOrgRepo)
+	pseudonyms := NewPseudonymizer([]byte("test-master-key"))
+	service.SetPrivacyPolicy(models.SnapshotPrivacyPolicy{Identifiers: models.IdentifierPolicyPseudonymise}, pseudonyms)
+
+	ctx := context.Background()
+	timestamp := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	employee := &models.Employee{
+		EmployeeID: "emp_123",
+		Name:       "John Doe",
+		Email:      "john.doe@example.com",
+		UnitID:     "unit_456",
+		TenantID:   "tenant_a",
+	}
+
+	mockEmployeeRepo.On("GetByID", ctx, "emp_123").Return(employee, nil)
+	mockOrgRepo.On("GetUnitAtTime", ctx, "unit_456", timestamp).Return(&models.OrgUnit{UnitID: "unit_456"}, nil)
+
+	snapshot, err := service.CaptureSnapshot(ctx, "emp_123", timestamp)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, snapshot.SnapshotCore, "employee_name")
+	assert.Equal(t, pseudonyms.Pseudonymize("tenant_a", "emp_123"), snapshot.SnapshotCore["employee_pseudonym"])
+	assert.NotEqual(t, pseudonyms.Pseudonymize("tenant_a", "emp_123"), pseudonyms.Pseudonymize("tenant_b", "emp_123"))
+}
+
+// TestDashboardRedactsIdentifiers tests that raw identifiers require an elevated permission
+func TestDashboardRedactsIdentifiers(t *testing.T) {
+	newResponses := func() []models.Response {
+		return []models.Response{{
+			ResponseID:   "resp_1",
+			EmployeeID:   "emp_123",
+			VersionID:    "emp_123_1700000000",
+			SnapshotCore: map[string]interface{}{"employee_name": "John Doe", "department": "Sales"},
+		}}
+	}
+	query := models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical, TenantID: "tenant_demo"}
+
+	mockResponseRepo := new(MockResponseRepository)
+	mockResponseRepo.On("Query", mock.Anything, query).Return(newResponses(), nil).Once()
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+
+	result, err := service.Query(context.Background(), query)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Responses[0].EmployeeID)
+	assert.Empty(t, result.Responses[0].VersionID)
+	assert.NotContains(t, result.Responses[0].SnapshotCore, "employee_name")
+	assert.Equal(t, "Sales", result.Responses[0].SnapshotCore["department"])
+
+	mockResponseRepo.On("Query", mock.Anything, query).Return(newResponses(), nil).Once()
+	ctx := WithPermissions(context.Background(), PermissionViewIdentifiers)
+
+	result, err = service.Query(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, "emp_123", result.Responses[0].EmployeeID)
+	assert.Equal(t, "emp_123_1700000000", result.Responses[0].VersionID)
+	assert.Equal(t, "John Doe", result.Responses[0].SnapshotCore["employee_name"])
+}
+
+// TestSubmitIdempotencyReplay tests that a retried key returns the original response,
+// and that a key reused for a different submission is rejected
+func TestSubmitIdempotencyReplay(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockSurveyRepo := new(MockSurveyRepository)
+	snapshotSvc := NewSnapshotService(new(MockEmployeeRepository), new(MockOrgRepository))
+	service := NewResponseService(mockResponseRepo, mockSurveyRepo, snapshotSvc)
+
+	ctx := context.Background()
+	answers := map[string]interface{}{"q1": 5.0}
+	hash, err := requestHash("survey_001", "emp_123", answers)
+	assert.NoError(t, err)
+	original := &models.Response{ResponseID: "resp_1", SurveyID: "survey_001", EmployeeID: "emp_123", RequestHash: hash}
+	mockResponseRepo.On("GetByIdempotencyKey", ctx, "tenant_demo", "key_1").Return(original, nil)
+
+	response, err := service.Submit(ctx, "survey_001", "emp_123", "tenant_demo", map[string]interface{}{"q1": 5.0}, "key_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "resp_1", response.ResponseID)
+
+	_, err = service.Submit(ctx, "survey_002", "emp_123", "tenant_demo", answers, "key_1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	// Same survey and employee, different answers
+	_, err = service.Submit(ctx, "survey_001", "emp_123", "tenant_demo", map[string]interface{}{"q1": 1.0}, "key_1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	mockResponseRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// TestSubmitSingleResponseSurvey tests that the survey's single-response rule reaches storage
+func TestSubmitSingleResponseSurvey(t *testing.T) {
+	mockEmployeeRepo := new(MockEmployeeRepository)
+	mockOrgRepo := new(MockOrgRepository)
+	mockResponseRepo := new(MockResponseRepository)
+	mockSurveyRepo := new(MockSurveyRepository)
+	service := NewResponseService(mockResponseRepo, mockSurveyRepo, NewSnapshotService(mockEmployeeRepo, mockOrgRepo))
+
+	ctx := context.Background()
+	mockResponseRepo.On("GetByIdempotencyKey", ctx, "tenant_demo", "key_2").Return(nil, nil)
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "survey_001").Return(&models.Survey{SingleResponse: true, Status: models.SurveyStatusOpen}, nil)
+	mockEmployeeRepo.On("GetByID", ctx, "emp_123").Return(&models.Employee{EmployeeID: "emp_123", UnitID: "unit_456"}, nil)
+	mockOrgRepo.On("GetUnitAtTime", ctx, "unit_456", mock.Anything).Return(&models.OrgUnit{UnitID: "unit_456"}, nil)
+	mockResponseRepo.On("Create", ctx, mock.MatchedBy(func(r *models.Response) bool {
+		return r.OnePerEmployee && r.IdempotencyKey == "key_2" && string(r.Answers) == `{"q1":5}`
+	})).Return(repository.ErrDuplicateResponse)
+
+	_, err := service.Submit(ctx, "survey_001", "emp_123", "tenant_demo", map[string]interface{}{"q1": 5}, "key_2")
+
+	assert.ErrorIs(t, err, repository.ErrDuplicateResponse)
+	mockResponseRepo.AssertExpectations(t)
+}
+
+// TestUpdateKeepsSnapshot tests that edits keep the original snapshot unless the survey opts in
+func TestUpdateKeepsSnapshot(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockSurveyRepo := new(MockSurveyRepository)
+	mockEmployeeRepo := new(MockEmployeeRepository)
+	service := NewResponseService(mockResponseRepo, mockSurveyRepo, NewSnapshotService(mockEmployeeRepo, new(MockOrgRepository)))
+
+	ctx := context.Background()
+	stored := &models.Response{
+		ResponseID:   "resp_1",
+		SurveyID:     "survey_001",
+		EmployeeID:   "emp_123",
+		TenantID:     "tenant_demo",
+		SnapshotCore: map[string]interface{}{"department": "Sales"},
+		VersionID:    "emp_123_1700000000",
+	}
+	mockResponseRepo.On("GetByID", ctx, "resp_1").Return(stored, nil)
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "survey_001").Return(&models.Survey{Status: models.SurveyStatusOpen}, nil)
+	mockResponseRepo.On("Update", ctx, mock.Anything, "emp_123").Return(nil)
+
+	response, err := service.Update(ctx, "tenant_demo", "resp_1", "emp_123", map[string]interface{}{"q1": 4})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Sales", response.SnapshotCore["department"])
+	assert.Equal(t, "emp_123_1700000000", response.VersionID)
+	assert.JSONEq(t, `{"q1":4}`, string(response.Answers))
+	mockEmployeeRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+
+	_, err = service.Update(ctx, "tenant_demo", "resp_1", "emp_999", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+// TestWithdrawAndVoid tests respondent withdrawal and admin voiding
+func TestWithdrawAndVoid(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	service := NewResponseService(mockResponseRepo, new(MockSurveyRepository), nil)
+
+	ctx := context.Background()
+	mockResponseRepo.On("GetByID", ctx, "resp_1").Return(&models.Response{EmployeeID: "emp_123", TenantID: "tenant_demo"}, nil)
+	mockResponseRepo.On("SetStatus", mock.Anything, "tenant_demo", "resp_1", models.ResponseStatusWithdrawn, "emp_123", "changed my mind").Return(nil)
+
+	assert.NoError(t, service.Withdraw(ctx, "tenant_demo", "resp_1", "emp_123", "changed my mind"))
+	assert.ErrorIs(t, service.Void(ctx, "tenant_demo", "resp_1", "admin_1", "fraud"), ErrPermissionDenied)
+
+	adminCtx := WithPermissions(ctx, PermissionManageResponses)
+	mockResponseRepo.On("SetStatus", adminCtx, "tenant_demo", "resp_1", models.ResponseStatusVoided, "admin_1", "fraud").Return(nil)
+	assert.NoError(t, service.Void(adminCtx, "tenant_demo", "resp_1", "admin_1", "fraud"))
+	mockResponseRepo.AssertExpectations(t)
+}
+
 // TestCalculateAge tests the age calculation function
 func TestCalculateAge(t *testing.T) {
 	tests := []struct {
@@ -141,6 +510,24 @@ func TestCalculateAge(t *testing.T) {
 			asOf:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
 			expected:  34,
 		},
+		{
+			name:      "Birthday today after leap year",
+			birthDate: time.Date(1990, 3, 1, 0, 0, 0, 0, time.UTC),
+			asOf:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected:  34,
+		},
+		{
+			name:      "Born in leap year, birthday today",
+			birthDate: time.Date(2000, 3, 1, 0, 0, 0, 0, time.UTC),
+			asOf:      time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected:  23,
+		},
+		{
+			name:      "Leap day birthday in non-leap year",
+			birthDate: time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC),
+			asOf:      time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC),
+			expected:  22,
+		},
 	}
 
 	for _, tt := range tests {
@@ -160,6 +547,50 @@ func TestCalculateTenure(t *testing.T) {
 
 	// Should be approximately 4.8 years
 	assert.InDelta(t, 4.8, result, 0.1)
+
+	// Calendar months, not 365.25-day years
+	assert.Equal(t, 1.0, calculateTenure(time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 0.0, calculateTenure(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+// TestBandFor tests band assignment boundaries
+func TestBandFor(t *testing.T) {
+	bands := DefaultBandConfig()
+
+	assert.Equal(t, "<25", bandFor(bands.AgeBands, 24))
+	assert.Equal(t, "25-34", bandFor(bands.AgeBands, 25))
+	assert.Equal(t, "25-34", bandFor(bands.AgeBands, 34))
+	assert.Equal(t, "55+", bandFor(bands.AgeBands, 70))
+	assert.Equal(t, "1-3y", bandFor(bands.TenureBands, 1))
+	assert.Equal(t, "3-5y", bandFor(bands.TenureBands, 3))
+	assert.Equal(t, "unknown", bandFor(bands.TenureBands, -0.5))
+}
+
+// TestDashboardGroupBy tests that group-by queries attach aggregated groups
+func TestDashboardGroupBy(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+
+	ctx := context.Background()
+	query := models.DashboardQuery{
+		TimeRange:  testTimeRange,
+		FilterMode: models.FilterModeHistorical,
+		TenantID:   "tenant_demo",
+		GroupBy:    []string{"age_band"},
+	}
+	groups := []models.GroupResult{
+		{Key: map[string]string{"age_band": "25-34"}, Count: 12},
+		{Key: map[string]string{"age_band": "35-44"}, Count: 7},
+	}
+
+	mockResponseRepo.On("Query", ctx, query).Return([]models.Response{}, nil)
+	mockResponseRepo.On("Aggregate", ctx, query).Return(groups, nil)
+
+	result, err := service.Query(ctx, query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, groups, result.Groups)
+	mockResponseRepo.AssertExpectations(t)
 }
 
 // Benchmark tests
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// ErrResponsesSuppressed is returned when too few responses are selected to stream them
+var ErrResponsesSuppressed = errors.New("responses suppressed below the anonymity threshold")
+
+// StreamResponses calls fn for every response the query selects, newest first, as rows
+// are read from the database. Unlike Query there is no row limit and no aggregation,
+// so memory stays flat however large the result. Iteration stops at the first error
+// from fn or when ctx is cancelled.
+//
+// Without PermissionViewIdentifiers the selection must reach the anonymity threshold,
+// raw identifiers are redacted as in Query and free-text answers are withheld, as in
+// comments and exports.
+func (s *DashboardService) StreamResponses(ctx context.Context, query models.DashboardQuery, fn func(*models.Response) error) error {
+	query, err := s.rowQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	identified := HasPermission(ctx, PermissionViewIdentifiers)
+	if !identified {
+		total, err := s.countResponses(ctx, query)
+		if err != nil {
+			return err
+		}
+		// The count itself is below the threshold, so it is not reported
+		if total < s.anonymityThreshold || total == 0 {
+			return ErrResponsesSuppressed
+		}
+	}
+
+	freeText := make(map[string]map[string]bool)
+	return s.eachResponse(ctx, query, func(r *models.Response) error {
+		if !identified {
+			redactResponse(r)
+			if err := s.withholdFreeText(ctx, query.TenantID, r, freeText); err != nil {
+				return err
+			}
+		}
+		return fn(r)
+	})
+}
+
+// countResponses returns how many responses a prepared query selects
+func (s *DashboardService) countResponses(ctx context.Context, query models.DashboardQuery) (int, error) {
+	query.GroupBy, query.Metrics, query.Benchmarks = nil, nil, nil
+	groups, err := s.responseRepo.Aggregate(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, g := range groups {
+		total += g.Count
+	}
+	return total, nil
+}
+
+// withholdFreeText drops the free-text answers of a response. freeText caches the
+// free-text question IDs per survey; surveys without question definitions map to nil
+// and lose every answer, as any of them may be free text.
+func (s *DashboardService) withholdFreeText(ctx context.Context, tenantID string, r *models.Response, freeText map[string]map[string]bool) error {
+	ids, ok := freeText[r.SurveyID]
+	if !ok {
+		survey, err := s.surveyRepo.GetByID(ctx, tenantID, r.SurveyID)
+		if err != nil {
+			return fmt.Errorf("failed to get survey: %w", err)
+		}
+		if survey != nil && len(survey.Questions) > 0 {
+			ids = make(map[string]bool)
+			for _, q := range survey.Questions {
+				if q.Type == models.QuestionTypeFreeText {
+					ids[q.QuestionID] = true
+				}
+			}
+		}
+		freeText[r.SurveyID] = ids
+	}
+
+	if ids == nil {
+		r.Answers = json.RawMessage(`{}`)
+		return nil
+	}
+	if len(ids) == 0 || len(r.Answers) == 0 {
+		return nil
+	}
+
+	var answers map[string]json.RawMessage
+	if err := json.Unmarshal(r.Answers, &answers); err != nil {
+		return fmt.Errorf("failed to decode answers of %s: %w", r.ResponseID, err)
+	}
+	for id := range ids {
+		delete(answers, id)
+	}
+	encoded, err := json.Marshal(answers)
+	if err != nil {
+		return fmt.Errorf("failed to encode answers of %s: %w", r.ResponseID, err)
+	}
+	r.Answers = encoded
+	return nil
+}
+
+// eachResponse iterates the responses a prepared query selects
+func (s *DashboardService) eachResponse(ctx context.Context, query models.DashboardQuery, fn func(*models.Response) error) error {
+	it, err := s.responseRepo.Iterate(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(it.Response()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func streamedResponses() []models.Response {
+	return []models.Response{
+		{ResponseID: "r1", SurveyID: "s1", EmployeeID: "emp_1", SnapshotCore: map[string]interface{}{"employee_email": "a@example.com", "department": "Sales"},
+			Answers: json.RawMessage(`{"q1":4,"q3":"My manager is great"}`)},
+		{ResponseID: "r2", SurveyID: "s1", EmployeeID: "emp_2", SnapshotCore: map[string]interface{}{"department": "Sales"}},
+		{ResponseID: "r3", SurveyID: "legacy", EmployeeID: "emp_3", SnapshotCore: map[string]interface{}{"department": "R&D"},
+			Answers: json.RawMessage(`{"comment":"Too many meetings"}`)},
+	}
+}
+
+// newStreamService returns a service whose query selects count responses
+func newStreamService(count int) (*DashboardService, *MockResponseRepository) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockSurveyRepo := new(MockSurveyRepository)
+	mockResponseRepo.On("Aggregate", mock.Anything, mock.Anything).Return([]models.GroupResult{{Count: count}}, nil)
+	mockSurveyRepo.On("GetByID", mock.Anything, mock.Anything, "s1").Return(exportSurvey(), nil)
+	mockSurveyRepo.On("GetByID", mock.Anything, mock.Anything, "legacy").Return(nil, nil)
+	return NewDashboardService(mockResponseRepo, new(MockOrgRepository), mockSurveyRepo), mockResponseRepo
+}
+
+// TestStreamResponsesRedacts tests that streamed rows are redacted like Query results
+// and lose their free-text answers
+func TestStreamResponsesRedacts(t *testing.T) {
+	service, mockResponseRepo := newStreamService(8)
+
+	ctx := context.Background()
+	mockResponseRepo.On("Iterate", ctx, mock.Anything).Return(streamedResponses(), nil)
+
+	var streamed []models.Response
+	err := service.StreamResponses(ctx, models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical}, func(r *models.Response) error {
+		streamed = append(streamed, *r)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, streamed, 3)
+	assert.Empty(t, streamed[0].EmployeeID)
+	assert.NotContains(t, streamed[0].SnapshotCore, "employee_email")
+	assert.JSONEq(t, `{"q1":4}`, string(streamed[0].Answers))
+	assert.Empty(t, streamed[1].Answers)
+	assert.JSONEq(t, `{}`, string(streamed[2].Answers), "a survey without definitions may hold free text anywhere")
+}
+
+// TestStreamResponsesThreshold tests that small selections are only streamed to
+// callers who may see identifiers
+func TestStreamResponsesThreshold(t *testing.T) {
+	query := models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical}
+
+	t.Run("suppressed", func(t *testing.T) {
+		service, mockResponseRepo := newStreamService(3)
+
+		rows := 0
+		err := service.StreamResponses(context.Background(), query, func(r *models.Response) error {
+			rows++
+			return nil
+		})
+
+		assert.ErrorIs(t, err, ErrResponsesSuppressed)
+		assert.Zero(t, rows)
+		mockResponseRepo.AssertNotCalled(t, "Iterate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("identified", func(t *testing.T) {
+		service, mockResponseRepo := newStreamService(3)
+		ctx := WithPermissions(context.Background(), PermissionViewIdentifiers)
+		mockResponseRepo.On("Iterate", ctx, mock.Anything).Return(streamedResponses(), nil)
+
+		var streamed []models.Response
+		err := service.StreamResponses(ctx, query, func(r *models.Response) error {
+			streamed = append(streamed, *r)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, streamed, 3)
+		assert.Equal(t, "emp_1", streamed[0].EmployeeID)
+		assert.Contains(t, string(streamed[0].Answers), "My manager is great")
+		mockResponseRepo.AssertNotCalled(t, "Aggregate", mock.Anything, mock.Anything)
+	})
+}
+
+// TestStreamResponsesStops tests that a failing consumer or a cancelled request ends the stream
+func TestStreamResponsesStops(t *testing.T) {
+	query := models.DashboardQuery{TimeRange: testTimeRange, FilterMode: models.FilterModeHistorical}
+	errWrite := errors.New("broken pipe")
+
+	t.Run("consumer error", func(t *testing.T) {
+		service, mockResponseRepo := newStreamService(8)
+		ctx := context.Background()
+		mockResponseRepo.On("Iterate", ctx, mock.Anything).Return(streamedResponses(), nil)
+
+		rows := 0
+		err := service.StreamResponses(ctx, query, func(r *models.Response) error {
+			rows++
+			if rows == 2 {
+				return errWrite
+			}
+			return nil
+		})
+
+		assert.ErrorIs(t, err, errWrite)
+		assert.Equal(t, 2, rows)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		service, mockResponseRepo := newStreamService(8)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		mockResponseRepo.On("Iterate", ctx, mock.Anything).Return(streamedResponses(), nil)
+
+		rows := 0
+		err := service.StreamResponses(ctx, query, func(r *models.Response) error {
+			rows++
+			return nil
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, rows)
+	})
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+)
+
+const (
+	defaultLikertMin     = 1
+	defaultLikertMax     = 5
+	defaultFreeTextLimit = 2000
+)
+
+const (
+	// MaxAnswers is the most answers one submission may carry
+	MaxAnswers = 200
+	// MaxAnswersSize is the largest JSON encoding of a submission's answers, in bytes
+	MaxAnswersSize = 64 << 10
+
+	maxEmployeeIDLength = 128
+	maxQuestionIDLength = 100
+)
+
+var (
+	// ErrSurveyNotOpen is returned for submissions outside the survey's state or window
+	ErrSurveyNotOpen = repository.NewKindError(repository.ErrConflict, "survey is not accepting responses")
+	// ErrNotEligible is returned when the employee is not in the survey's population
+	ErrNotEligible = repository.NewKindError(ErrPermissionDenied, "employee is not eligible for this survey")
+	// ErrInvalidTransition is returned for a survey state change the lifecycle forbids
+	ErrInvalidTransition = repository.NewKindError(repository.ErrConflict, "invalid survey status transition")
+)
+
+// surveyTransitions lists the states each survey state may move to
+var surveyTransitions = map[models.SurveyStatus][]models.SurveyStatus{
+	models.SurveyStatusDraft:  {models.SurveyStatusOpen},
+	models.SurveyStatusOpen:   {models.SurveyStatusClosed},
+	models.SurveyStatusClosed: {models.SurveyStatusOpen, models.SurveyStatusArchived},
+}
+
+// SurveyService manages survey definitions
+type SurveyService struct {
+	surveyRepo repository.SurveyRepository
+}
+
+func NewSurveyService(surveyRepo repository.SurveyRepository) *SurveyService {
+	return &SurveyService{surveyRepo: surveyRepo}
+}
+
+// Create validates and stores a new survey definition
+func (s *SurveyService) Create(ctx context.Context, survey *models.Survey) error {
+	if !HasPermission(ctx, PermissionManageSurveys) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageSurveys)
+	}
+	if err := validateSurvey(survey); err != nil {
+		return err
+	}
+	return s.surveyRepo.Create(ctx, survey)
+}
+
+// Update validates and replaces an existing survey definition
+func (s *SurveyService) Update(ctx context.Context, survey *models.Survey) error {
+	if !HasPermission(ctx, PermissionManageSurveys) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageSurveys)
+	}
+	if err := validateSurvey(survey); err != nil {
+		return err
+	}
+	return s.surveyRepo.Update(ctx, survey)
+}
+
+// Delete removes a survey definition; stored responses are kept
+func (s *SurveyService) Delete(ctx context.Context, tenantID, surveyID string) error {
+	if !HasPermission(ctx, PermissionManageSurveys) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageSurveys)
+	}
+	return s.surveyRepo.Delete(ctx, tenantID, surveyID)
+}
+
+// Get returns a survey definition
+func (s *SurveyService) Get(ctx context.Context, tenantID, surveyID string) (*models.Survey, error) {
+	survey, err := s.surveyRepo.GetByID(ctx, tenantID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey == nil {
+		return nil, fmt.Errorf("survey %w: %s", repository.ErrNotFound, surveyID)
+	}
+	return survey, nil
+}
+
+// List returns the tenant's survey definitions
+func (s *SurveyService) List(ctx context.Context, tenantID string) ([]models.Survey, error) {
+	return s.surveyRepo.List(ctx, tenantID)
+}
+
+// Transition moves a survey through its lifecycle. Opening a draft launches it,
+// capturing the eligible population; reopening a closed survey keeps that population.
+func (s *SurveyService) Transition(ctx context.Context, tenantID, surveyID string, to models.SurveyStatus) (*models.Survey, error) {
+	if !HasPermission(ctx, PermissionManageSurveys) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, PermissionManageSurveys)
+	}
+
+	survey, err := s.Get(ctx, tenantID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(statusStrings(surveyTransitions[survey.Status]), string(to)) {
+		return nil, fmt.Errorf("%w: %s → %s", ErrInvalidTransition, survey.Status, to)
+	}
+
+	if survey.Status == models.SurveyStatusDraft {
+		if len(survey.Audience.EmployeeIDs) == 0 && len(survey.Audience.UnitIDs) == 0 {
+			verr := &ValidationError{}
+			verr.Add("audience", "required", "an audience is required to launch")
+			return nil, verr
+		}
+		if _, err := s.surveyRepo.Launch(ctx, survey); err != nil {
+			return nil, err
+		}
+		return survey, nil
+	}
+
+	if err := s.surveyRepo.SetStatus(ctx, tenantID, surveyID, survey.Status, to); err != nil {
+		return nil, err
+	}
+	survey.Status = to
+	return survey, nil
+}
+
+// Participation returns the response rate against the population captured at launch
+func (s *SurveyService) Participation(ctx context.Context, tenantID, surveyID string) (*models.Participation, error) {
+	survey, err := s.Get(ctx, tenantID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey.LaunchedAt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSurveyNotLaunched, surveyID)
+	}
+	return s.surveyRepo.GetParticipation(ctx, tenantID, surveyID)
+}
+
+// acceptsResponses checks the survey's state, window and population for employeeID
+func acceptsResponses(ctx context.Context, surveyRepo repository.SurveyRepository, survey *models.Survey, employeeID string, now time.Time) error {
+	if survey.Status != models.SurveyStatusOpen {
+		return fmt.Errorf("%w: survey is %s", ErrSurveyNotOpen, survey.Status)
+	}
+	if survey.OpensAt != nil && now.Before(*survey.OpensAt) {
+		return fmt.Errorf("%w: opens at %s", ErrSurveyNotOpen, survey.OpensAt.Format(time.RFC3339))
+	}
+	if survey.ClosesAt != nil && !now.Before(*survey.ClosesAt) {
+		return fmt.Errorf("%w: closed at %s", ErrSurveyNotOpen, survey.ClosesAt.Format(time.RFC3339))
+	}
+
+	// Legacy surveys opened before populations were captured accept everyone
+	if survey.LaunchedAt == nil {
+		return nil
+	}
+	eligible, err := surveyRepo.IsEligible(ctx, survey.TenantID, survey.SurveyID, employeeID)
+	if err != nil {
+		return err
+	}
+	if !eligible {
+		return ErrNotEligible
+	}
+	return nil
+}
+
+func statusStrings(statuses []models.SurveyStatus) []string {
+	out := make([]string, len(statuses))
+	for i, st := range statuses {
+		out[i] = string(st)
+	}
+	return out
+}
+
+// validateSurvey checks that a survey definition is internally consistent
+func validateSurvey(survey *models.Survey) error {
+	verr := &ValidationError{}
+	if survey.SurveyID == "" {
+		verr.Add("survey_id", "required", "survey_id is required")
+	}
+
+	if survey.OpensAt != nil && survey.ClosesAt != nil && !survey.OpensAt.Before(*survey.ClosesAt) {
+		verr.Add("closes_at", "invalid_range", "closes_at must be after opens_at")
+	}
+
+	seen := make(map[string]bool)
+	for i, q := range survey.Questions {
+		field := fmt.Sprintf("questions[%d]", i)
+		if q.QuestionID == "" {
+			verr.Add(field+".question_id", "required", "question_id is required")
+		} else if seen[q.QuestionID] {
+			verr.Add(field+".question_id", "duplicate", "question_id %q is used twice", q.QuestionID)
+		}
+		seen[q.QuestionID] = true
+
+		switch q.Type {
+		case models.QuestionTypeLikert:
+			min, max := likertRange(q)
+			if min >= max {
+				verr.Add(field+".max", "invalid_range", "max must be greater than min")
+			}
+		case models.QuestionTypeSingleChoice, models.QuestionTypeMultiChoice:
+			if len(q.Options) == 0 {
+				verr.Add(field+".options", "required", "choice questions need options")
+			}
+		case models.QuestionTypeNPS, models.QuestionTypeFreeText:
+		default:
+			verr.Add(field+".type", "invalid", "unknown question type %q", q.Type)
+		}
+	}
+
+	return verr.OrNil()
+}
+
+// validateAnswers checks answers against the survey's questions. Surveys without
+// questions (legacy definitions) accept any answers.
+func validateAnswers(survey *models.Survey, answers map[string]interface{}) error {
+	if survey == nil || len(survey.Questions) == 0 {
+		return nil
+	}
+
+	verr := &ValidationError{}
+	known := make(map[string]bool, len(survey.Questions))
+
+	for _, q := range survey.Questions {
+		known[q.QuestionID] = true
+		field := "answers." + q.QuestionID
+
+		value, ok := answers[q.QuestionID]
+		if !ok || value == nil {
+			if q.Required {
+				verr.Add(field, "required", "answer is required")
+			}
+			continue
+		}
+		validateAnswer(verr, field, q, value)
+	}
+
+	// Report unknown IDs in a stable order
+	var unknown []string
+	for id := range answers {
+		if !known[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	sort.Strings(unknown)
+	for _, id := range unknown {
+		verr.Add("answers."+id, "unknown_question", "survey has no question %q", id)
+	}
+
+	return verr.OrNil()
+}
+
+// validateSubmission checks what every submission needs regardless of the survey, so
+// answers to legacy surveys without question definitions are bounded too
+func validateSubmission(employeeID string, answers map[string]interface{}) error {
+	verr := &ValidationError{}
+	switch {
+	case strings.TrimSpace(employeeID) == "":
+		verr.Add("employee_id", "required", "employee_id is required")
+	case len(employeeID) > maxEmployeeIDLength:
+		verr.Add("employee_id", "too_long", "employee_id exceeds %d characters", maxEmployeeIDLength)
+	}
+
+	if len(answers) > MaxAnswers {
+		verr.Add("answers", "too_many", "at most %d answers are accepted", MaxAnswers)
+	} else if encoded, err := json.Marshal(answers); err != nil {
+		verr.Add("answers", "invalid_type", "answers must be JSON values")
+	} else if len(encoded) > MaxAnswersSize {
+		verr.Add("answers", "too_long", "answers exceed %d bytes", MaxAnswersSize)
+	}
+
+	ids := make([]string, 0, len(answers))
+	for id := range answers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if id == "" || len(id) > maxQuestionIDLength {
+			verr.Add("answers."+id, "invalid", "question IDs must be 1 to %d characters", maxQuestionIDLength)
+		}
+	}
+
+	return verr.OrNil()
+}
+
+func validateAnswer(verr *ValidationError, field string, q models.Question, value interface{}) {
+	switch q.Type {
+	case models.QuestionTypeLikert:
+		min, max := likertRange(q)
+		validateScale(verr, field, value, min, max)
+
+	case models.QuestionTypeNPS:
+		validateScale(verr, field, value, 0, 10)
+
+	case models.QuestionTypeSingleChoice:
+		choice, ok := value.(string)
+		if !ok {
+			verr.Add(field, "invalid_type", "expected a string")
+			return
+		}
+		if !contains(q.Options, choice) {
+			verr.Add(field, "invalid_option", "%q is not an option", choice)
+		}
+
+	case models.QuestionTypeMultiChoice:
+		choices, ok := value.([]interface{})
+		if !ok {
+			verr.Add(field, "invalid_type", "expected an array of strings")
+			return
+		}
+		seen := make(map[string]bool, len(choices))
+		for _, c := range choices {
+			choice, ok := c.(string)
+			if !ok {
+				verr.Add(field, "invalid_type", "expected an array of strings")
+				return
+			}
+			if !contains(q.Options, choice) {
+				verr.Add(field, "invalid_option", "%q is not an option", choice)
+			} else if seen[choice] {
+				verr.Add(field, "duplicate", "%q is selected twice", choice)
+			}
+			seen[choice] = true
+		}
+
+	case models.QuestionTypeFreeText:
+		text, ok := value.(string)
+		if !ok {
+			verr.Add(field, "invalid_type", "expected a string")
+			return
+		}
+		limit := q.MaxLength
+		if limit == 0 {
+			limit = defaultFreeTextLimit
+		}
+		if len([]rune(text)) > limit {
+			verr.Add(field, "too_long", "must be at most %d characters", limit)
+		}
+	}
+}
+
+// validateScale accepts whole numbers in [min, max]. JSON numbers decode as float64.
+func validateScale(verr *ValidationError, field string, value interface{}, min, max int) {
+	n, ok := value.(float64)
+	if !ok {
+		if i, isInt := value.(int); isInt {
+			n, ok = float64(i), true
+		}
+	}
+	if !ok || n != math.Trunc(n) {
+		verr.Add(field, "invalid_type", "expected a whole number")
+		return
+	}
+	if n < float64(min) || n > float64(max) {
+		verr.Add(field, "out_of_range", "must be between %d and %d", min, max)
+	}
+}
+
+func likertRange(q models.Question) (int, int) {
+	min, max := defaultLikertMin, defaultLikertMax
+	if q.Min != nil {
+		min = *q.Min
+	}
+	if q.Max != nil {
+		max = *q.Max
+	}
+	return min, max
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+	"dashboard-case-study/pkg/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testSurvey() *models.Survey {
+	seven := 7
+	return &models.Survey{
+		SurveyID: "survey_001",
+		Questions: []models.Question{
+			{QuestionID: "q1", Type: models.QuestionTypeLikert, Required: true},
+			{QuestionID: "q2", Type: models.QuestionTypeLikert, Max: &seven},
+			{QuestionID: "enps", Type: models.QuestionTypeNPS},
+			{QuestionID: "team", Type: models.QuestionTypeSingleChoice, Options: []string{"A", "B"}},
+			{QuestionID: "perks", Type: models.QuestionTypeMultiChoice, Options: []string{"gym", "lunch"}},
+			{QuestionID: "comment", Type: models.QuestionTypeFreeText, MaxLength: 10},
+		},
+	}
+}
+
+// decodeAnswers mimics answers as decoded from a JSON request body
+func decodeAnswers(t *testing.T, raw string) map[string]interface{} {
+	var answers map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(raw), &answers))
+	return answers
+}
+
+// TestValidateAnswers tests per-question validation of submitted answers
+func TestValidateAnswers(t *testing.T) {
+	tests := []struct {
+		name     string
+		answers  string
+		expected []FieldError
+	}{
+		{
+			name:    "Valid answers",
+			answers: `{"q1": 5, "q2": 7, "enps": 0, "team": "B", "perks": ["gym", "lunch"], "comment": "great"}`,
+		},
+		{
+			name:    "Missing required answer",
+			answers: `{"q2": 3}`,
+			expected: []FieldError{
+				{Field: "answers.q1", Code: "required", Message: "answer is required"},
+			},
+		},
+		{
+			name:    "Out of range and unknown question",
+			answers: `{"q1": 6, "enps": 11, "q9": 1}`,
+			expected: []FieldError{
+				{Field: "answers.q1", Code: "out_of_range", Message: "must be between 1 and 5"},
+				{Field: "answers.enps", Code: "out_of_range", Message: "must be between 0 and 10"},
+				{Field: "answers.q9", Code: "unknown_question", Message: `survey has no question "q9"`},
+			},
+		},
+		{
+			name:    "Wrong types and options",
+			answers: `{"q1": 2.5, "team": "C", "perks": ["gym", "gym"], "comment": "far too long"}`,
+			expected: []FieldError{
+				{Field: "answers.q1", Code: "invalid_type", Message: "expected a whole number"},
+				{Field: "answers.team", Code: "invalid_option", Message: `"C" is not an option`},
+				{Field: "answers.perks", Code: "duplicate", Message: `"gym" is selected twice`},
+				{Field: "answers.comment", Code: "too_long", Message: "must be at most 10 characters"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnswers(testSurvey(), decodeAnswers(t, tt.answers))
+			if tt.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			assert.True(t, errors.As(err, &verr))
+			assert.Equal(t, tt.expected, verr.Errors)
+		})
+	}
+}
+
+// TestValidateAnswersLegacySurvey tests that surveys without questions accept anything
+func TestValidateAnswersLegacySurvey(t *testing.T) {
+	assert.NoError(t, validateAnswers(nil, map[string]interface{}{"anything": true}))
+	assert.NoError(t, validateAnswers(&models.Survey{}, map[string]interface{}{"anything": true}))
+}
+
+// TestCreateSurveyValidatesDefinition tests survey definition checks before storage
+func TestCreateSurveyValidatesDefinition(t *testing.T) {
+	mockSurveyRepo := new(MockSurveyRepository)
+	service := NewSurveyService(mockSurveyRepo)
+	ctx := WithPermissions(context.Background(), PermissionManageSurveys)
+
+	survey := &models.Survey{
+		SurveyID: "survey_001",
+		Questions: []models.Question{
+			{QuestionID: "q1", Type: models.QuestionTypeLikert},
+			{QuestionID: "q1", Type: "SLIDER"},
+			{QuestionID: "q3", Type: models.QuestionTypeSingleChoice},
+		},
+	}
+
+	err := service.Create(ctx, survey)
+
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Len(t, verr.Errors, 3)
+	mockSurveyRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	assert.ErrorIs(t, service.Create(context.Background(), testSurvey()), ErrPermissionDenied)
+
+	mockSurveyRepo.On("Create", ctx, mock.Anything).Return(nil)
+	assert.NoError(t, service.Create(ctx, testSurvey()))
+}
+
+// TestAcceptsResponses tests survey state, window and eligibility checks on submit
+func TestAcceptsResponses(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	launched := now.Add(-24 * time.Hour)
+
+	mockSurveyRepo := new(MockSurveyRepository)
+	mockSurveyRepo.On("IsEligible", mock.Anything, "tenant_demo", "survey_001", "emp_in").Return(true, nil)
+	mockSurveyRepo.On("IsEligible", mock.Anything, "tenant_demo", "survey_001", "emp_out").Return(false, nil)
+
+	tests := []struct {
+		name       string
+		survey     models.Survey
+		employeeID string
+		expected   error
+	}{
+		{"Draft survey", models.Survey{Status: models.SurveyStatusDraft}, "emp_in", ErrSurveyNotOpen},
+		{"Closed survey", models.Survey{Status: models.SurveyStatusClosed}, "emp_in", ErrSurveyNotOpen},
+		{"Before window", models.Survey{Status: models.SurveyStatusOpen, OpensAt: &after}, "emp_in", ErrSurveyNotOpen},
+		{"After window", models.Survey{Status: models.SurveyStatusOpen, ClosesAt: &before}, "emp_in", ErrSurveyNotOpen},
+		{"Legacy open survey", models.Survey{Status: models.SurveyStatusOpen}, "emp_out", nil},
+		{"Eligible employee", models.Survey{Status: models.SurveyStatusOpen, OpensAt: &before, ClosesAt: &after, LaunchedAt: &launched}, "emp_in", nil},
+		{"Ineligible employee", models.Survey{Status: models.SurveyStatusOpen, LaunchedAt: &launched}, "emp_out", ErrNotEligible},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.survey.SurveyID = "survey_001"
+			tt.survey.TenantID = "tenant_demo"
+			err := acceptsResponses(context.Background(), mockSurveyRepo, &tt.survey, tt.employeeID, now)
+			if tt.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSurveyTransition tests launching drafts and rejecting invalid transitions
+func TestSurveyTransition(t *testing.T) {
+	mockSurveyRepo := new(MockSurveyRepository)
+	service := NewSurveyService(mockSurveyRepo)
+	ctx := WithPermissions(context.Background(), PermissionManageSurveys)
+
+	draft := &models.Survey{
+		SurveyID: "survey_001",
+		TenantID: "tenant_demo",
+		Status:   models.SurveyStatusDraft,
+		Audience: models.SurveyAudience{UnitIDs: []string{"unit_sales"}},
+	}
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "survey_001").Return(draft, nil)
+	mockSurveyRepo.On("Launch", ctx, draft).Return(42, nil)
+
+	_, err := service.Transition(ctx, "tenant_demo", "survey_001", models.SurveyStatusArchived)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	_, err = service.Transition(ctx, "tenant_demo", "survey_001", models.SurveyStatusOpen)
+	assert.NoError(t, err)
+	mockSurveyRepo.AssertCalled(t, "Launch", ctx, draft)
+	mockSurveyRepo.AssertNotCalled(t, "SetStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestSurveyErrorKinds tests that survey errors match the kinds handlers map to statuses
+func TestSurveyErrorKinds(t *testing.T) {
+	mockSurveyRepo := new(MockSurveyRepository)
+	service := NewSurveyService(mockSurveyRepo)
+	ctx := WithPermissions(context.Background(), PermissionManageSurveys)
+
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "missing").Return(nil, nil)
+	mockSurveyRepo.On("GetByID", ctx, "tenant_demo", "survey_001").Return(&models.Survey{
+		SurveyID: "survey_001",
+		TenantID: "tenant_demo",
+		Status:   models.SurveyStatusDraft,
+	}, nil)
+
+	_, err := service.Get(ctx, "tenant_demo", "missing")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.EqualError(t, err, "survey not found: missing")
+
+	_, err = service.Transition(ctx, "tenant_demo", "survey_001", models.SurveyStatusArchived)
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	_, err = service.Participation(ctx, "tenant_demo", "survey_001")
+	assert.ErrorIs(t, err, ErrSurveyNotLaunched)
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	err = service.Create(ctx, &models.Survey{TenantID: "tenant_demo"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	assert.ErrorIs(t, ErrNotEligible, ErrPermissionDenied)
+	assert.ErrorIs(t, repository.ErrDuplicateResponse, repository.ErrConflict)
+	assert.NotErrorIs(t, repository.ErrDuplicateResponse, repository.ErrNotFound)
+}
+
+// TestValidateSubmission tests survey-independent limits on submissions
+func TestValidateSubmission(t *testing.T) {
+	assert.NoError(t, validateSubmission("emp_123", map[string]interface{}{"q1": 5.0}))
+
+	tooMany := make(map[string]interface{}, MaxAnswers+1)
+	for i := 0; i <= MaxAnswers; i++ {
+		tooMany[fmt.Sprintf("q%d", i)] = 1.0
+	}
+	assert.Equal(t, map[string]string{"employee_id": "required", "answers": "too_many"},
+		fieldCodes(t, validateSubmission("  ", tooMany)))
+
+	huge := map[string]interface{}{"comment": strings.Repeat("x", MaxAnswersSize), "": 1.0}
+	assert.Equal(t, map[string]string{"answers": "too_long", "answers.": "invalid"},
+		fieldCodes(t, validateSubmission("emp_123", huge)))
+
+	// Limits apply before the survey is loaded
+	service := NewResponseService(new(MockResponseRepository), new(MockSurveyRepository), nil)
+	_, err := service.Submit(context.Background(), "survey_001", "", "tenant_demo", nil, "")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
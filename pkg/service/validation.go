@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dashboard-case-study/pkg/models"
+)
+
+// ErrInvalidRequest is matched by errors for requests that can never succeed as sent,
+// including every *ValidationError
+var ErrInvalidRequest = errors.New("invalid request")
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every field error found in a request
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap makes validation errors match ErrInvalidRequest
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidRequest
+}
+
+// Add records a field error
+func (e *ValidationError) Add(field, code, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// OrNil returns e if any field error was recorded, otherwise nil
+func (e *ValidationError) OrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// validateDashboardQuery checks a query as the caller sent it and reports every
+// violation at once. Filter and group-by keys are only checked against schema when the
+// tenant has one, since a tenant without responses has no keys yet.
+func validateDashboardQuery(q models.DashboardQuery, schema models.SnapshotSchema) error {
+	verr := &ValidationError{}
+	validateQueryOptions(verr, "", q)
+
+	from, to := q.TimeRange.From, q.TimeRange.To
+	if from.IsZero() {
+		verr.Add("time_range.from", "required", "time_range.from is required")
+	}
+	if to.IsZero() {
+		verr.Add("time_range.to", "required", "time_range.to is required")
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		verr.Add("time_range", "invalid_range", "time_range.from must be before time_range.to")
+	}
+
+	validateFilters(verr, q, schema)
+	for i, field := range q.GroupBy {
+		switch {
+		case field == models.GroupByTimeBucket, field == models.GroupBySurvey:
+		case strings.HasPrefix(field, models.AnswerFieldPrefix):
+		case len(schema) > 0 && schema[field] == nil:
+			verr.Add(fmt.Sprintf("group_by[%d]", i), "unknown_field", "%q is not a snapshot attribute", field)
+		}
+	}
+
+	return verr.OrNil()
+}
+
+// validateQueryOptions checks the parts of a query that do not depend on the tenant.
+// Field names are prefixed with prefix, e.g. "widgets[0].query.".
+func validateQueryOptions(verr *ValidationError, prefix string, q models.DashboardQuery) {
+	switch q.FilterMode {
+	case models.FilterModeHistorical, models.FilterModeCurrent, models.FilterModeHybrid:
+	case "":
+		verr.Add(prefix+"filter_mode", "required", "filter_mode is required")
+	default:
+		verr.Add(prefix+"filter_mode", "invalid", "invalid filter mode %q", q.FilterMode)
+	}
+
+	validateMetrics(verr, prefix, q.Metrics)
+	validateTimeBucket(verr, prefix, q)
+	validateBenchmarks(verr, prefix, q)
+	validateAnswerFilters(verr, prefix, q.AnswerFilters)
+}
+
+// validateFilters checks that each filter names a snapshot attribute and that its value,
+// or each value of a list, has a type the attribute takes
+func validateFilters(verr *ValidationError, q models.DashboardQuery, schema models.SnapshotSchema) {
+	keys := make([]string, 0, len(q.Filters))
+	for key := range q.Filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := "filters." + key
+		value := q.Filters[key]
+
+		if strings.HasPrefix(key, models.AnswerFieldPrefix) {
+			if jsonType(value) == "" {
+				verr.Add(field, "invalid_type", "expected a string, number or boolean")
+			}
+			continue
+		}
+
+		types := schema[key]
+		if len(schema) > 0 && types == nil {
+			verr.Add(field, "unknown_field", "%q is not a snapshot attribute", key)
+			continue
+		}
+
+		// CURRENT mode maps a single department name to its historical units
+		if key == "department" && q.FilterMode != models.FilterModeHistorical {
+			if _, ok := value.(string); !ok {
+				verr.Add(field, "invalid_type", "expected a single department name in %s mode", q.FilterMode)
+				continue
+			}
+		}
+
+		values := []interface{}{value}
+		switch v := value.(type) {
+		case []interface{}:
+			values = v
+		case []string:
+			values = make([]interface{}, len(v))
+			for i, s := range v {
+				values[i] = s
+			}
+		}
+		if len(values) == 0 {
+			verr.Add(field, "required", "expected at least one value")
+			continue
+		}
+
+		for _, v := range values {
+			typ := jsonType(v)
+			if typ == "" {
+				verr.Add(field, "invalid_type", "expected a string, number, boolean or a list of them")
+				break
+			}
+			if types != nil && !contains(types, typ) {
+				verr.Add(field, "invalid_type", "expected %s, got %s", strings.Join(types, " or "), typ)
+				break
+			}
+		}
+	}
+}
+
+// jsonType returns the JSON type of a scalar filter value, or "" for anything else
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64, int:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"dashboard-case-study/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSchemaRepository struct {
+	mock.Mock
+}
+
+func (m *MockSchemaRepository) SnapshotSchema(ctx context.Context, tenantID string) (models.SnapshotSchema, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).(models.SnapshotSchema), args.Error(1)
+}
+
+func testSchema() models.SnapshotSchema {
+	return models.SnapshotSchema{
+		"department": {"string"},
+		"unit_id":    {"string"},
+		"age":        {"number"},
+		"age_band":   {"string"},
+	}
+}
+
+// fieldCodes maps each field of a validation error to its code
+func fieldCodes(t *testing.T, err error) map[string]string {
+	var verr *ValidationError
+	if !assert.True(t, errors.As(err, &verr)) {
+		return nil
+	}
+	codes := make(map[string]string, len(verr.Errors))
+	for _, fe := range verr.Errors {
+		codes[fe.Field] = fe.Code
+	}
+	return codes
+}
+
+// TestValidateDashboardQuery tests that every violation in a query is reported at once
+func TestValidateDashboardQuery(t *testing.T) {
+	err := validateDashboardQuery(models.DashboardQuery{
+		Filters: map[string]interface{}{
+			"location":   "SG",
+			"age":        "35",
+			"age_band":   []interface{}{"25-34", map[string]interface{}{}},
+			"answers.q1": nil,
+		},
+		GroupBy: []string{"grade", models.GroupByTimeBucket},
+		Metrics: []models.MetricSpec{{Type: "MEDIAN", QuestionID: "q1"}},
+	}, testSchema())
+
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, map[string]string{
+		"filter_mode":        "required",
+		"time_range.from":    "required",
+		"time_range.to":      "required",
+		"filters.location":   "unknown_field",
+		"filters.age":        "invalid_type",
+		"filters.age_band":   "invalid_type",
+		"filters.answers.q1": "invalid_type",
+		"group_by[0]":        "unknown_field",
+		"time_bucket":        "invalid",
+		"metrics[0].type":    "invalid",
+	}, fieldCodes(t, err))
+
+	valid := models.DashboardQuery{
+		FilterMode: models.FilterModeHistorical,
+		TimeRange:  testTimeRange,
+		Filters:    map[string]interface{}{"age": 35.0, "age_band": []interface{}{"25-34", "35-44"}},
+		GroupBy:    []string{"department"},
+	}
+	assert.NoError(t, validateDashboardQuery(valid, testSchema()))
+
+	valid.TimeRange.From, valid.TimeRange.To = valid.TimeRange.To, valid.TimeRange.From
+	assert.Equal(t, map[string]string{"time_range": "invalid_range"}, fieldCodes(t, validateDashboardQuery(valid, testSchema())))
+
+	// CURRENT mode maps one department name; without a schema keys are not checked
+	current := models.DashboardQuery{
+		FilterMode: models.FilterModeCurrent,
+		TimeRange:  testTimeRange,
+		Filters:    map[string]interface{}{"department": []interface{}{"Sales"}, "location": "SG"},
+	}
+	assert.Equal(t, map[string]string{"filters.department": "invalid_type"}, fieldCodes(t, validateDashboardQuery(current, nil)))
+}
+
+// TestQueryChecksTenantSchema tests that queries are checked against the tenant's cached schema
+func TestQueryChecksTenantSchema(t *testing.T) {
+	mockResponseRepo := new(MockResponseRepository)
+	mockSchemaRepo := new(MockSchemaRepository)
+	service := NewDashboardService(mockResponseRepo, new(MockOrgRepository), new(MockSurveyRepository))
+	service.SetSchemaRepository(mockSchemaRepo)
+
+	ctx := context.Background()
+	mockSchemaRepo.On("SnapshotSchema", ctx, "tenant_demo").Return(testSchema(), nil).Once()
+	mockResponseRepo.On("Query", ctx, mock.Anything).Return([]models.Response{}, nil)
+
+	query := models.DashboardQuery{
+		FilterMode: models.FilterModeHistorical,
+		TenantID:   "tenant_demo",
+		TimeRange:  models.TimeRange{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		Filters:    map[string]interface{}{"departmnet": "Sales"},
+	}
+	_, err := service.Query(ctx, query)
+	assert.Equal(t, map[string]string{"filters.departmnet": "unknown_field"}, fieldCodes(t, err))
+
+	query.Filters = map[string]interface{}{"department": "Sales"}
+	_, err = service.Query(ctx, query)
+	assert.NoError(t, err)
+	mockSchemaRepo.AssertExpectations(t)
+}